@@ -0,0 +1,172 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// CAP_CODE_ADD_PATH is the capability code for ADD-PATH (RFC 7911).
+const CAP_CODE_ADD_PATH = 69
+
+// AddPathSendReceive indicates the directionality a speaker advertises for
+// an AddPathCapability: whether it is willing to receive multiple paths for
+// an AFI/SAFI, send them, or both.
+type AddPathSendReceive uint8
+
+const (
+	ADD_PATH_RECEIVE      AddPathSendReceive = 1
+	ADD_PATH_SEND         AddPathSendReceive = 2
+	ADD_PATH_SEND_RECEIVE AddPathSendReceive = 3
+)
+
+// AddPathCapability is a single AFI/SAFI entry of the ADD-PATH capability
+// (RFC 7911), analogous to the existing MP capability's per-AFI/SAFI
+// entries. A speaker advertises one AddPathCapability per AFI/SAFI pair it
+// wants to negotiate ADD-PATH for.
+type AddPathCapability struct {
+	AFI         uint16
+	SAFI        uint8
+	SendReceive AddPathSendReceive
+}
+
+// Encode returns the wire encoding of a as a capability, including the
+// capability code and length header.
+func (a AddPathCapability) Encode() []byte {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint16(value[0:2], a.AFI)
+	value[2] = a.SAFI
+	value[3] = byte(a.SendReceive)
+	return append([]byte{CAP_CODE_ADD_PATH, byte(len(value))}, value...)
+}
+
+// DecodeAddPathCapabilities decodes b, the value portion of an ADD-PATH
+// capability, into its constituent per-AFI/SAFI entries. b's length must be
+// a non-zero multiple of 4.
+func DecodeAddPathCapabilities(b []byte) ([]AddPathCapability, error) {
+	if len(b) == 0 || len(b)%4 != 0 {
+		return nil, fmt.Errorf("corebgp: ADD-PATH capability value length %d is not a non-zero multiple of 4", len(b))
+	}
+	caps := make([]AddPathCapability, 0, len(b)/4)
+	for i := 0; i < len(b); i += 4 {
+		caps = append(caps, AddPathCapability{
+			AFI:         binary.BigEndian.Uint16(b[i : i+2]),
+			SAFI:        b[i+2],
+			SendReceive: AddPathSendReceive(b[i+3]),
+		})
+	}
+	return caps, nil
+}
+
+// addPathPathID decodes the 4-byte Path Identifier preceding a prefix in an
+// ADD-PATH NLRI/withdrawn routes encoding, returning it along with the
+// remainder of b starting at the prefix itself.
+func addPathPathID(b []byte) (uint32, []byte, error) {
+	if len(b) < 4 {
+		return 0, nil, fmt.Errorf("corebgp: ADD-PATH path identifier truncated")
+	}
+	return binary.BigEndian.Uint32(b[0:4]), b[4:], nil
+}
+
+// NewAddPathNLRIDecodeFn returns a function that decodes the NLRI portion
+// of an UPDATE message encoded per RFC 7911 ADD-PATH, i.e. with a 4-byte
+// Path Identifier preceding each IPv4 prefix, invoking fn with each decoded
+// (Path Identifier, prefix) pair. It is the ADD-PATH counterpart of
+// NewNLRIDecodeFn, for use constructing an UpdateDecoder against a peer
+// that negotiated ADD-PATH receive for AFI_IPV4/SAFI_UNICAST.
+func NewAddPathNLRIDecodeFn[M any](fn func(m M, pathID uint32, prefix netip.Prefix) error) func(m M, b []byte) error {
+	return func(m M, b []byte) error {
+		for len(b) > 0 {
+			pathID, rest, err := addPathPathID(b)
+			if err != nil {
+				return err
+			}
+			prefix, n, err := addPathDecodeIPv4Prefix(rest)
+			if err != nil {
+				return err
+			}
+			if err := fn(m, pathID, prefix); err != nil {
+				return err
+			}
+			b = rest[n:]
+		}
+		return nil
+	}
+}
+
+// NewAddPathWithdrawnRoutesDecodeFn returns a function that decodes the
+// withdrawn routes portion of an UPDATE message encoded per RFC 7911
+// ADD-PATH, invoking fn with each decoded (Path Identifier, prefix) pair.
+// It is the ADD-PATH counterpart of NewWithdrawnRoutesDecodeFn.
+func NewAddPathWithdrawnRoutesDecodeFn[M any](fn func(m M, pathID uint32, prefix netip.Prefix) error) func(m M, b []byte) error {
+	return NewAddPathNLRIDecodeFn(fn)
+}
+
+// AddPathPrefix pairs a decoded prefix with the RFC 7911 Path Identifier it
+// was advertised or withdrawn with.
+type AddPathPrefix struct {
+	PathID uint32
+	Prefix netip.Prefix
+}
+
+// DecodeAddPathMPIPv6Prefixes decodes b as a series of ADD-PATH IPv6
+// prefixes, each preceded by a 4-byte Path Identifier, suitable for the
+// NLRI or withdrawn routes portion of a MP_REACH_NLRI/MP_UNREACH_NLRI path
+// attribute for AFI_IPV6 when ADD-PATH has been negotiated. It is the
+// ADD-PATH counterpart of DecodeMPIPv6Prefixes.
+func DecodeAddPathMPIPv6Prefixes(b []byte) ([]AddPathPrefix, error) {
+	var prefixes []AddPathPrefix
+	for len(b) > 0 {
+		pathID, rest, err := addPathPathID(b)
+		if err != nil {
+			return nil, err
+		}
+		prefix, n, err := addPathDecodeIPv6Prefix(rest)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, AddPathPrefix{PathID: pathID, Prefix: prefix})
+		b = rest[n:]
+	}
+	return prefixes, nil
+}
+
+// addPathDecodeIPv4Prefix decodes a single <length, prefix> encoded IPv4 prefix
+// from the head of b, returning the prefix and the number of bytes
+// consumed.
+func addPathDecodeIPv4Prefix(b []byte) (netip.Prefix, int, error) {
+	if len(b) < 1 {
+		return netip.Prefix{}, 0, fmt.Errorf("corebgp: IPv4 prefix truncated")
+	}
+	bitLen := int(b[0])
+	if bitLen > 32 {
+		return netip.Prefix{}, 0, fmt.Errorf("corebgp: IPv4 prefix length %d exceeds 32", bitLen)
+	}
+	byteLen := (bitLen + 7) / 8
+	if len(b) < 1+byteLen {
+		return netip.Prefix{}, 0, fmt.Errorf("corebgp: IPv4 prefix truncated")
+	}
+	var ab [4]byte
+	copy(ab[:], b[1:1+byteLen])
+	return netip.PrefixFrom(netip.AddrFrom4(ab), bitLen), 1 + byteLen, nil
+}
+
+// addPathDecodeIPv6Prefix decodes a single <length, prefix> encoded IPv6 prefix
+// from the head of b, returning the prefix and the number of bytes
+// consumed.
+func addPathDecodeIPv6Prefix(b []byte) (netip.Prefix, int, error) {
+	if len(b) < 1 {
+		return netip.Prefix{}, 0, fmt.Errorf("corebgp: IPv6 prefix truncated")
+	}
+	bitLen := int(b[0])
+	if bitLen > 128 {
+		return netip.Prefix{}, 0, fmt.Errorf("corebgp: IPv6 prefix length %d exceeds 128", bitLen)
+	}
+	byteLen := (bitLen + 7) / 8
+	if len(b) < 1+byteLen {
+		return netip.Prefix{}, 0, fmt.Errorf("corebgp: IPv6 prefix truncated")
+	}
+	var ab [16]byte
+	copy(ab[:], b[1:1+byteLen])
+	return netip.PrefixFrom(netip.AddrFrom16(ab), bitLen), 1 + byteLen, nil
+}
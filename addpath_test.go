@@ -0,0 +1,58 @@
+package corebgp
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestNewAddPathNLRIDecodeFn(t *testing.T) {
+	b := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x08, 0x0a, // path id 1, 10.0.0.0/8
+		0x00, 0x00, 0x00, 0x02, 0x10, 0x0a, 0x00, // path id 2, 10.0.0.0/16
+	}
+	type result struct {
+		pathID uint32
+		prefix netip.Prefix
+	}
+	var got []result
+	fn := NewAddPathNLRIDecodeFn(func(m *[]result, pathID uint32, prefix netip.Prefix) error {
+		*m = append(*m, result{pathID, prefix})
+		return nil
+	})
+	if err := fn(&got, b); err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	want := []result{
+		{1, netip.MustParsePrefix("10.0.0.0/8")},
+		{2, netip.MustParsePrefix("10.0.0.0/16")},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %+v != got: %+v", want, got)
+	}
+}
+
+func TestDecodeAddPathMPIPv6Prefixes(t *testing.T) {
+	b := []byte{
+		0x00, 0x00, 0x00, 0x2a, // path id 42
+		0x40, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, // 2001:db8::/64
+	}
+	got, err := DecodeAddPathMPIPv6Prefixes(b)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	want := []AddPathPrefix{
+		{PathID: 42, Prefix: netip.MustParsePrefix("2001:db8::/64")},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %+v != got: %+v", want, got)
+	}
+}
+
+func TestAddPathCapability_Encode(t *testing.T) {
+	c := AddPathCapability{AFI: AFI_IPV6, SAFI: SAFI_UNICAST, SendReceive: ADD_PATH_SEND_RECEIVE}
+	want := []byte{CAP_CODE_ADD_PATH, 0x04, 0x00, 0x02, 0x01, 0x03}
+	if got := c.Encode(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %x != got: %x", want, got)
+	}
+}
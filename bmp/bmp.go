@@ -0,0 +1,59 @@
+// Package bmp implements a BMP (BGP Monitoring Protocol, RFC 7854)
+// exporter that streams messages derived from a corebgp session to one or
+// more collectors. It does not itself speak BGP; callers wire Exporter's
+// methods into their corebgp.Plugin implementation at the points where
+// they already observe OPEN messages, session state changes, and raw
+// UPDATE message bytes (e.g. via corebgp.UpdateDecoder.Decode).
+package bmp
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// BMP message types (RFC 7854 section 4.1), the Msg Type octet of the
+// Common Header.
+const (
+	MSG_TYPE_ROUTE_MONITORING  = 0
+	MSG_TYPE_STATISTICS_REPORT = 1
+	MSG_TYPE_PEER_DOWN         = 2
+	MSG_TYPE_PEER_UP           = 3
+	MSG_TYPE_INITIATION        = 4
+	MSG_TYPE_TERMINATION       = 5
+	MSG_TYPE_ROUTE_MIRRORING   = 6
+)
+
+// bmpVersion is the BMP version this package implements (RFC 7854 section
+// 4.1).
+const bmpVersion = 3
+
+// encodeCommonHeader returns the 6-octet Common Header (RFC 7854 section
+// 4.1) for a message of msgType whose body is bodyLen octets long.
+func encodeCommonHeader(msgType uint8, bodyLen int) []byte {
+	b := make([]byte, 6)
+	b[0] = bmpVersion
+	binary.BigEndian.PutUint32(b[1:5], uint32(6+bodyLen))
+	b[5] = msgType
+	return b
+}
+
+// encodeMessage prepends the Common Header to body and returns the
+// complete BMP message.
+func encodeMessage(msgType uint8, body []byte) []byte {
+	return append(encodeCommonHeader(msgType, len(body)), body...)
+}
+
+// encodeTLV appends an RFC 7854 Information TLV (type, length, value) to b.
+func encodeTLV(b []byte, typ uint16, value []byte) []byte {
+	var hdr [4]byte
+	binary.BigEndian.PutUint16(hdr[0:2], typ)
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(value)))
+	b = append(b, hdr[:]...)
+	return append(b, value...)
+}
+
+// bmpTimestamp encodes t as the seconds/microseconds pair used by the
+// Per-Peer Header and Peer Down/Up messages.
+func bmpTimestamp(t time.Time) (seconds, micros uint32) {
+	return uint32(t.Unix()), uint32(t.Nanosecond() / 1000)
+}
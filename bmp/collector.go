@@ -0,0 +1,123 @@
+package bmp
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// defaultMinBackoff and defaultMaxBackoff bound the reconnect delay used
+// between attempts to (re)dial a collector.
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// Collector is a single BMP collector TCP endpoint that Exporter maintains
+// a connection to, reconnecting with exponential backoff if the connection
+// is lost.
+type Collector struct {
+	addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewCollector returns a Collector for the given "host:port" address. It
+// does not dial until used by an Exporter.
+func NewCollector(addr string) *Collector {
+	return &Collector{addr: addr}
+}
+
+// run dials c in a loop with exponential backoff, redialing whenever the
+// connection is lost, until ctx is canceled. initFn is called to produce
+// the mandatory Initiation message (RFC 7854 section 4.3), sent as the
+// first write on every successful (re)connect.
+func (c *Collector) run(ctx context.Context, initFn func() []byte) {
+	backoff := defaultMinBackoff
+	for {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.addr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("bmp: dial %s: %v, retrying in %s", c.addr, err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		if _, err := conn.Write(initFn()); err != nil {
+			log.Printf("bmp: write initiation to %s: %v, retrying in %s", c.addr, err, backoff)
+			conn.Close()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = defaultMinBackoff
+		c.mu.Lock()
+		c.conn = conn
+		c.mu.Unlock()
+
+		lost := make(chan struct{})
+		go func() {
+			// BMP is one-way (the collector never writes back), so this
+			// read only ever unblocks once conn is closed or reset, which
+			// is how a dropped connection is detected.
+			var buf [1]byte
+			conn.Read(buf[:])
+			close(lost)
+		}()
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return
+		case <-lost:
+		}
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+		conn.Close()
+	}
+}
+
+func nextBackoff(b time.Duration) time.Duration {
+	b *= 2
+	if b > defaultMaxBackoff {
+		return defaultMaxBackoff
+	}
+	return b
+}
+
+// write sends b to the collector if currently connected, silently dropping
+// it otherwise (run sends a fresh Initiation, resynchronizing the
+// collector, as soon as the connection is reestablished).
+func (c *Collector) write(b []byte) {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	if _, err := conn.Write(b); err != nil {
+		log.Printf("bmp: write to %s: %v", c.addr, err)
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+		conn.Close()
+	}
+}
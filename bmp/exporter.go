@@ -0,0 +1,265 @@
+package bmp
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Exporter turns observations from one or more corebgp sessions into BMP
+// messages and streams them to a set of collectors. Callers create an
+// Exporter, Start it, and then call its RouteMonitoring/PeerUp/PeerDown/
+// StatsReport methods from the points in their corebgp.Plugin
+// implementation where the corresponding event is already observed.
+type Exporter struct {
+	collectors []*Collector
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	statsInterval time.Duration
+	statsCancel   map[PeerInfo]context.CancelFunc
+	statsMu       sync.Mutex
+}
+
+// NewExporter returns an Exporter that streams to the given collectors.
+// statsInterval is the period of the Stats Reports that PeerUp starts for
+// each peer; a zero value disables periodic stats reports (StatsReport
+// can still be called directly).
+func NewExporter(statsInterval time.Duration, collectors ...*Collector) *Exporter {
+	return &Exporter{
+		collectors:    collectors,
+		statsInterval: statsInterval,
+		statsCancel:   make(map[PeerInfo]context.CancelFunc),
+	}
+}
+
+// Start begins the reconnect/backoff loop for each collector. Each
+// collector sends the mandatory Initiation message as the first write on
+// every (re)connect, so a collector that (re)joins late or reconnects
+// after a dropped connection is always resynchronized.
+func (e *Exporter) Start(ctx context.Context, sysDescr, sysName string) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	initFn := func() []byte { return e.initiationMessage(sysDescr, sysName) }
+	for _, c := range e.collectors {
+		e.wg.Add(1)
+		go func(c *Collector) {
+			defer e.wg.Done()
+			c.run(ctx, initFn)
+		}(c)
+	}
+}
+
+// Stop tears down all collector connections and stops any running
+// periodic Stats Reports.
+func (e *Exporter) Stop() {
+	e.statsMu.Lock()
+	for _, cancel := range e.statsCancel {
+		cancel()
+	}
+	e.statsCancel = make(map[PeerInfo]context.CancelFunc)
+	e.statsMu.Unlock()
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.wg.Wait()
+}
+
+func (e *Exporter) broadcast(b []byte) {
+	for _, c := range e.collectors {
+		c.write(b)
+	}
+}
+
+// Initiation TLV types (RFC 7854 section 4.3).
+const (
+	initTLVSysDescr = 1
+	initTLVSysName  = 2
+)
+
+func (e *Exporter) initiationMessage(sysDescr, sysName string) []byte {
+	var body []byte
+	if sysDescr != "" {
+		body = encodeTLV(body, initTLVSysDescr, []byte(sysDescr))
+	}
+	if sysName != "" {
+		body = encodeTLV(body, initTLVSysName, []byte(sysName))
+	}
+	return encodeMessage(MSG_TYPE_INITIATION, body)
+}
+
+// Termination message reason codes (RFC 7854 section 4.5).
+const (
+	TERM_REASON_ADMIN_CLOSE              = 0
+	TERM_REASON_UNSPECIFIED              = 1
+	TERM_REASON_OUT_OF_RESOURCES         = 2
+	TERM_REASON_REDUNDANT_CONNECTION     = 3
+	TERM_REASON_PERMANENTLY_ADMIN_CLOSED = 4
+)
+
+const termTLVReason = 1
+
+// Terminate sends a Termination message to all collectors with the given
+// reason, then calls Stop.
+func (e *Exporter) Terminate(reason uint16) {
+	var reasonValue [2]byte
+	binary.BigEndian.PutUint16(reasonValue[:], reason)
+	body := encodeTLV(nil, termTLVReason, reasonValue[:])
+	e.broadcast(encodeMessage(MSG_TYPE_TERMINATION, body))
+	e.Stop()
+}
+
+// RouteMonitoring sends a Route Monitoring message carrying the raw wire
+// bytes of a single UPDATE message (the same bytes passed to
+// corebgp.UpdateDecoder.Decode), wrapped with a Per-Peer Header for peer.
+// Call it twice per received UPDATE -- once with postPolicy false as the
+// message is received (pre-policy adj-RIB-in), and again with postPolicy
+// true after the caller's policy has been applied -- to support both
+// monitoring points described in RFC 7854 section 5.
+func (e *Exporter) RouteMonitoring(peer PeerInfo, postPolicy bool, rawUpdate []byte) {
+	header := encodePerPeerHeader(peer, time.Now(), postPolicy)
+	body := append(header, rawUpdate...)
+	e.broadcast(encodeMessage(MSG_TYPE_ROUTE_MONITORING, body))
+}
+
+// PeerUp sends a Peer Up Notification for peer, carrying the sent and
+// received OPEN message bytes captured during session establishment, and
+// (if statsInterval was configured) starts a periodic Stats Report for the
+// peer using statsFn until PeerDown is called for the same PeerInfo.
+func (e *Exporter) PeerUp(peer PeerInfo, localAddr netip.Addr, localPort, remotePort uint16, sentOpen, recvOpen []byte, statsFn func() Stats) {
+	body := make([]byte, 0, 20+len(sentOpen)+len(recvOpen))
+	if localAddr.Is6() {
+		ab := localAddr.As16()
+		body = append(body, ab[:]...)
+	} else {
+		body = append(body, make([]byte, 12)...)
+		ab := localAddr.As4()
+		body = append(body, ab[:]...)
+	}
+	var portBuf [2]byte
+	binary.BigEndian.PutUint16(portBuf[:], localPort)
+	body = append(body, portBuf[:]...)
+	binary.BigEndian.PutUint16(portBuf[:], remotePort)
+	body = append(body, portBuf[:]...)
+	body = append(body, sentOpen...)
+	body = append(body, recvOpen...)
+
+	header := encodePerPeerHeader(peer, time.Now(), false)
+	e.broadcast(encodeMessage(MSG_TYPE_PEER_UP, append(header, body...)))
+
+	if e.statsInterval <= 0 || statsFn == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.statsMu.Lock()
+	e.statsCancel[peer] = cancel
+	e.statsMu.Unlock()
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.statsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.StatsReport(peer, statsFn())
+			}
+		}
+	}()
+}
+
+// Peer Down reason codes (RFC 7854 section 4.9).
+const (
+	PEER_DOWN_REASON_LOCAL_NOTIFICATION     = 1
+	PEER_DOWN_REASON_LOCAL_NO_NOTIFICATION  = 2
+	PEER_DOWN_REASON_REMOTE_NOTIFICATION    = 3
+	PEER_DOWN_REASON_REMOTE_NO_NOTIFICATION = 4
+	PEER_DOWN_REASON_PEER_DE_CONFIGURED     = 5
+)
+
+// PeerDown sends a Peer Down Notification for peer and stops any periodic
+// Stats Report started by PeerUp. notifBytes is appended after the reason
+// code as given, whatever the reason; for PEER_DOWN_REASON_LOCAL_NOTIFICATION
+// or PEER_DOWN_REASON_REMOTE_NOTIFICATION it is the wire-encoded
+// NOTIFICATION that caused the session to go down.
+func (e *Exporter) PeerDown(peer PeerInfo, reason uint8, notifBytes []byte) {
+	e.statsMu.Lock()
+	if cancel, ok := e.statsCancel[peer]; ok {
+		cancel()
+		delete(e.statsCancel, peer)
+	}
+	e.statsMu.Unlock()
+
+	body := append([]byte{reason}, notifBytes...)
+	header := encodePerPeerHeader(peer, time.Now(), false)
+	e.broadcast(encodeMessage(MSG_TYPE_PEER_DOWN, append(header, body...)))
+}
+
+// Stats holds the counters reported in a Stats Report message. Counts that
+// are not tracked by the caller should be left at 0; AdjRIBInInvalid and
+// AdjRIBInDiscarded are intended to be sourced from the corebgp UpdateErr
+// types: TreatAsWithdrawUpdateErr and AttrDiscardUpdateErr respectively.
+type Stats struct {
+	AdjRIBIn          uint64
+	AdjRIBInInvalid   uint64
+	AdjRIBInDiscarded uint64
+	Withdrawn         uint64
+	DuplicatePrefix   uint64
+}
+
+// Stats Report TLV types (RFC 7854 section 4.8). Types 0-6, 11, and 12 are
+// 32-bit counters; type 7 (Adj-RIB-In route count) is a 64-bit gauge.
+const (
+	statTLVRejectedByPolicy  = 0
+	statTLVDuplicatePrefix   = 1
+	statTLVDuplicateWithdraw = 2
+	statTLVAdjRIBIn          = 7
+	statTLVAdjRIBInInvalid   = 11
+)
+
+// statTLV is a single Stats Report counter: its RFC 7854 type, the wire
+// width of its Stat Data (4 bytes for a counter, 8 for a gauge), and value.
+type statTLV struct {
+	typ   uint16
+	width int
+	value uint64
+}
+
+// StatsReport sends a Stats Report message for peer with the given
+// counters.
+func (e *Exporter) StatsReport(peer PeerInfo, s Stats) {
+	tlvs := []statTLV{
+		{statTLVAdjRIBIn, 8, s.AdjRIBIn},
+		{statTLVDuplicatePrefix, 4, s.DuplicatePrefix},
+		{statTLVDuplicateWithdraw, 4, s.Withdrawn},
+		{statTLVAdjRIBInInvalid, 4, s.AdjRIBInInvalid},
+		// AttrDiscardUpdateErr discards a malformed optional attribute but
+		// keeps the route, which RFC 7854 has no dedicated counter for; it
+		// is reported as policy-rejected, the closest defined meaning of
+		// "a prefix's update was not applied as received".
+		{statTLVRejectedByPolicy, 4, s.AdjRIBInDiscarded},
+	}
+	var count [4]byte
+	binary.BigEndian.PutUint32(count[:], uint32(len(tlvs)))
+
+	var stats []byte
+	for _, tlv := range tlvs {
+		var vb [8]byte
+		switch tlv.width {
+		case 4:
+			binary.BigEndian.PutUint32(vb[:4], uint32(tlv.value))
+			stats = encodeTLV(stats, tlv.typ, vb[:4])
+		case 8:
+			binary.BigEndian.PutUint64(vb[:], tlv.value)
+			stats = encodeTLV(stats, tlv.typ, vb[:])
+		}
+	}
+	body := append(count[:], stats...)
+
+	header := encodePerPeerHeader(peer, time.Now(), false)
+	e.broadcast(encodeMessage(MSG_TYPE_STATISTICS_REPORT, append(header, body...)))
+}
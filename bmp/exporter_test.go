@@ -0,0 +1,38 @@
+package bmp
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestExporter_RouteMonitoring(t *testing.T) {
+	c := NewCollector("127.0.0.1:0") // never dialed in this test
+	e := NewExporter(0, c)
+
+	peer := PeerInfo{
+		BGPID:   netip.MustParseAddr("192.0.2.1"),
+		AS:      65001,
+		Address: netip.MustParseAddr("192.0.2.2"),
+		Type:    PEER_TYPE_GLOBAL_INSTANCE,
+	}
+	rawUpdate := []byte{0x00, 0x00, 0x00, 0x00} // empty UPDATE (end-of-rib)
+
+	// RouteMonitoring should not panic or block even with no connected
+	// collectors; that is what this test checks, not the message shape.
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("RouteMonitoring panicked: %v", r)
+		}
+	}()
+	e.RouteMonitoring(peer, false, rawUpdate)
+}
+
+func TestEncodeCommonHeader(t *testing.T) {
+	hdr := encodeCommonHeader(MSG_TYPE_INITIATION, 10)
+	if hdr[0] != bmpVersion {
+		t.Fatalf("want version %d, got %d", bmpVersion, hdr[0])
+	}
+	if hdr[5] != MSG_TYPE_INITIATION {
+		t.Fatalf("want msg type %d, got %d", MSG_TYPE_INITIATION, hdr[5])
+	}
+}
@@ -0,0 +1,70 @@
+package bmp
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"time"
+)
+
+// Per-Peer header types (RFC 7854 section 4.2).
+const (
+	PEER_TYPE_GLOBAL_INSTANCE = 0
+	PEER_TYPE_RD_INSTANCE     = 1
+	PEER_TYPE_LOCAL_INSTANCE  = 2
+)
+
+// Per-Peer header flag bits (RFC 7854 section 4.2).
+const (
+	PEER_FLAG_IPV6             = 1 << 7
+	PEER_FLAG_POST_POLICY      = 1 << 6
+	PEER_FLAG_LEGACY_AS_FORMAT = 1 << 5
+	PEER_FLAG_ADJ_RIB_OUT      = 1 << 4
+)
+
+// PeerInfo identifies a monitored BGP peer for the purposes of the Per-Peer
+// Header attached to every Route Monitoring, Stats Report, and Peer
+// Up/Down message.
+type PeerInfo struct {
+	// BGPID is the peer's BGP Identifier (router ID).
+	BGPID netip.Addr
+	// AS is the peer's autonomous system number.
+	AS uint32
+	// Address is the peer's transport address.
+	Address netip.Addr
+	// RD is the peer's Route Distinguisher, used only when Type is
+	// PEER_TYPE_RD_INSTANCE.
+	RD [8]byte
+	// Type is one of the PEER_TYPE_* constants.
+	Type uint8
+}
+
+// encodePerPeerHeader encodes the 42-octet Per-Peer Header (RFC 7854
+// section 4.2) for p at time ts. postPolicy marks the L flag, indicating
+// the enclosed route has passed the peer's inbound policy.
+func encodePerPeerHeader(p PeerInfo, ts time.Time, postPolicy bool) []byte {
+	b := make([]byte, 42)
+	b[0] = p.Type
+	var flags uint8
+	if p.Address.Is6() {
+		flags |= PEER_FLAG_IPV6
+	}
+	if postPolicy {
+		flags |= PEER_FLAG_POST_POLICY
+	}
+	b[1] = flags
+	copy(b[2:10], p.RD[:])
+	if p.Address.Is6() {
+		ab := p.Address.As16()
+		copy(b[10:26], ab[:])
+	} else {
+		ab := p.Address.As4()
+		copy(b[22:26], ab[:])
+	}
+	binary.BigEndian.PutUint32(b[26:30], p.AS)
+	bgpID := p.BGPID.As4()
+	copy(b[30:34], bgpID[:])
+	seconds, micros := bmpTimestamp(ts)
+	binary.BigEndian.PutUint32(b[34:38], seconds)
+	binary.BigEndian.PutUint32(b[38:42], micros)
+	return b
+}
@@ -0,0 +1,401 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// Path attribute type codes for the community and scalar attributes added
+// here, following the numbering used alongside PATH_ATTR_ORIGIN et al.
+const (
+	PATH_ATTR_MULTI_EXIT_DISC      = 4
+	PATH_ATTR_LOCAL_PREF           = 5
+	PATH_ATTR_AGGREGATOR           = 7
+	PATH_ATTR_COMMUNITIES          = 8
+	PATH_ATTR_ORIGINATOR_ID        = 9
+	PATH_ATTR_CLUSTER_LIST         = 10
+	PATH_ATTR_EXTENDED_COMMUNITIES = 16
+	PATH_ATTR_AS4_AGGREGATOR       = 18
+	PATH_ATTR_LARGE_COMMUNITIES    = 32
+)
+
+// MultiExitDiscPathAttr is the PATH_ATTR_MULTI_EXIT_DISC (MED) path
+// attribute, an optional non-transitive uint32 metric used in the BGP best
+// path selection process (RFC 4271 section 5.1.4).
+type MultiExitDiscPathAttr uint32
+
+// Decode decodes b, the value portion of a PATH_ATTR_MULTI_EXIT_DISC path
+// attribute, into m. A length other than 4 or flags other than optional
+// non-transitive is a malformed attribute; per RFC 7606 section 7 this is
+// returned as a TreatAsWithdrawUpdateErr rather than an AttrDiscardUpdateErr.
+func (m *MultiExitDiscPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b) != 4 || !checkAttrFlags(flags, true, false) {
+		return &TreatAsWithdrawUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_MULTI_EXIT_DISC, flags, b)}
+	}
+	*m = MultiExitDiscPathAttr(binary.BigEndian.Uint32(b))
+	return nil
+}
+
+// Encode returns the wire encoding of m, including header.
+func (m MultiExitDiscPathAttr) Encode() []byte {
+	var vb [4]byte
+	binary.BigEndian.PutUint32(vb[:], uint32(m))
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_MULTI_EXIT_DISC, vb[:])
+}
+
+// LocalPrefPathAttr is the PATH_ATTR_LOCAL_PREF path attribute, a
+// well-known discretionary uint32 sent between peers within the same AS
+// (RFC 4271 section 5.1.5).
+type LocalPrefPathAttr uint32
+
+// Decode decodes b, the value portion of a PATH_ATTR_LOCAL_PREF path
+// attribute, into l. A length other than 4 or flags other than well-known
+// transitive is a malformed attribute; per RFC 7606 section 7 this is
+// returned as a TreatAsWithdrawUpdateErr rather than an AttrDiscardUpdateErr.
+func (l *LocalPrefPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b) != 4 || !checkAttrFlags(flags, false, true) {
+		return &TreatAsWithdrawUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_LOCAL_PREF, flags, b)}
+	}
+	*l = LocalPrefPathAttr(binary.BigEndian.Uint32(b))
+	return nil
+}
+
+// Encode returns the wire encoding of l, including header.
+func (l LocalPrefPathAttr) Encode() []byte {
+	var vb [4]byte
+	binary.BigEndian.PutUint32(vb[:], uint32(l))
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_LOCAL_PREF, vb[:])
+}
+
+// AggregatorPathAttr is the PATH_ATTR_AGGREGATOR path attribute, carrying
+// the AS number and BGP-ID of the speaker that aggregated a route (RFC
+// 4271 section 5.1.7). AS is two-octet; use AS4AggregatorPathAttr for the
+// four-octet AS number variant negotiated via AS4 capability.
+type AggregatorPathAttr struct {
+	AS      uint16
+	Speaker netip.Addr
+}
+
+// Decode decodes b, the value portion of a PATH_ATTR_AGGREGATOR path
+// attribute, into a. A length other than 6 or flags other than optional
+// transitive is returned as an AttrDiscardUpdateErr.
+func (a *AggregatorPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b) != 6 || !checkAttrFlags(flags, true, true) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_AGGREGATOR, flags, b)}
+	}
+	speaker, ok := netip.AddrFromSlice(b[2:6])
+	if !ok {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_AGGREGATOR, flags, b)}
+	}
+	a.AS = binary.BigEndian.Uint16(b[0:2])
+	a.Speaker = speaker
+	return nil
+}
+
+// Encode returns the wire encoding of a, including header.
+func (a AggregatorPathAttr) Encode() []byte {
+	value := make([]byte, 0, 6)
+	var asb [2]byte
+	binary.BigEndian.PutUint16(asb[:], a.AS)
+	value = append(value, asb[:]...)
+	ab := a.Speaker.As4()
+	value = append(value, ab[:]...)
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE|PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_AGGREGATOR, value)
+}
+
+// AS4AggregatorPathAttr is the PATH_ATTR_AS4_AGGREGATOR path attribute (RFC
+// 6793), the four-octet AS number counterpart to AggregatorPathAttr, sent
+// by speakers that have not negotiated four-octet AS number capability with
+// a peer but still want to preserve the true aggregator AS.
+type AS4AggregatorPathAttr struct {
+	AS      uint32
+	Speaker netip.Addr
+}
+
+// Decode decodes b, the value portion of a PATH_ATTR_AS4_AGGREGATOR path
+// attribute, into a. A length other than 8 or flags other than optional
+// transitive is returned as an AttrDiscardUpdateErr.
+func (a *AS4AggregatorPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b) != 8 || !checkAttrFlags(flags, true, true) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_AS4_AGGREGATOR, flags, b)}
+	}
+	speaker, ok := netip.AddrFromSlice(b[4:8])
+	if !ok {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_AS4_AGGREGATOR, flags, b)}
+	}
+	a.AS = binary.BigEndian.Uint32(b[0:4])
+	a.Speaker = speaker
+	return nil
+}
+
+// Encode returns the wire encoding of a, including header.
+func (a AS4AggregatorPathAttr) Encode() []byte {
+	value := make([]byte, 0, 8)
+	var asb [4]byte
+	binary.BigEndian.PutUint32(asb[:], a.AS)
+	value = append(value, asb[:]...)
+	ab := a.Speaker.As4()
+	value = append(value, ab[:]...)
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE|PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_AS4_AGGREGATOR, value)
+}
+
+// OriginatorIDPathAttr is the PATH_ATTR_ORIGINATOR_ID path attribute (RFC
+// 4456), carrying the BGP-ID of the route's originator within a route
+// reflection cluster.
+type OriginatorIDPathAttr netip.Addr
+
+// Decode decodes b, the value portion of a PATH_ATTR_ORIGINATOR_ID path
+// attribute, into o. A length other than 4 or flags other than optional
+// non-transitive is returned as an AttrDiscardUpdateErr.
+func (o *OriginatorIDPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b) != 4 || !checkAttrFlags(flags, true, false) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_ORIGINATOR_ID, flags, b)}
+	}
+	id, ok := netip.AddrFromSlice(b)
+	if !ok {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_ORIGINATOR_ID, flags, b)}
+	}
+	*o = OriginatorIDPathAttr(id)
+	return nil
+}
+
+// Encode returns the wire encoding of o, including header.
+func (o OriginatorIDPathAttr) Encode() []byte {
+	ab := netip.Addr(o).As4()
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_ORIGINATOR_ID, ab[:])
+}
+
+// ClusterListPathAttr is the PATH_ATTR_CLUSTER_LIST path attribute (RFC
+// 4456), a sequence of CLUSTER_ID values identifying the route reflection
+// clusters a route has traversed.
+type ClusterListPathAttr struct {
+	ClusterIDs []uint32
+}
+
+// Decode decodes b, the value portion of a PATH_ATTR_CLUSTER_LIST path
+// attribute, into c. A length that is not a multiple of 4 or flags other
+// than optional non-transitive is returned as an AttrDiscardUpdateErr.
+func (c *ClusterListPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b)%4 != 0 || !checkAttrFlags(flags, true, false) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_CLUSTER_LIST, flags, b)}
+	}
+	ids := make([]uint32, 0, len(b)/4)
+	for i := 0; i < len(b); i += 4 {
+		ids = append(ids, binary.BigEndian.Uint32(b[i:i+4]))
+	}
+	c.ClusterIDs = ids
+	return nil
+}
+
+// Encode returns the wire encoding of c, including header.
+func (c ClusterListPathAttr) Encode() []byte {
+	value := make([]byte, 0, 4*len(c.ClusterIDs))
+	for _, id := range c.ClusterIDs {
+		var ib [4]byte
+		binary.BigEndian.PutUint32(ib[:], id)
+		value = append(value, ib[:]...)
+	}
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_CLUSTER_LIST, value)
+}
+
+// Community is a single RFC 1997 community value, commonly rendered as
+// ASN:value.
+type Community uint32
+
+// ASN returns the high-order 16 bits of c.
+func (c Community) ASN() uint16 {
+	return uint16(c >> 16)
+}
+
+// Value returns the low-order 16 bits of c.
+func (c Community) Value() uint16 {
+	return uint16(c)
+}
+
+// CommunitiesPathAttr is the PATH_ATTR_COMMUNITIES path attribute (RFC
+// 1997).
+type CommunitiesPathAttr struct {
+	Communities []Community
+}
+
+// Decode decodes b, the value portion of a PATH_ATTR_COMMUNITIES path
+// attribute, into c. A length that is not a multiple of 4 or flags other
+// than optional transitive is returned as an AttrDiscardUpdateErr.
+func (c *CommunitiesPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b)%4 != 0 || !checkAttrFlags(flags, true, true) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_COMMUNITIES, flags, b)}
+	}
+	communities := make([]Community, 0, len(b)/4)
+	for i := 0; i < len(b); i += 4 {
+		communities = append(communities, Community(binary.BigEndian.Uint32(b[i:i+4])))
+	}
+	c.Communities = communities
+	return nil
+}
+
+// Encode returns the wire encoding of c, including header.
+func (c CommunitiesPathAttr) Encode() []byte {
+	value := make([]byte, 0, 4*len(c.Communities))
+	for _, comm := range c.Communities {
+		var cb [4]byte
+		binary.BigEndian.PutUint32(cb[:], uint32(comm))
+		value = append(value, cb[:]...)
+	}
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_COMMUNITIES, value)
+}
+
+// LargeCommunity is a single RFC 8092 large community value.
+type LargeCommunity struct {
+	GlobalAdmin uint32
+	LocalData1  uint32
+	LocalData2  uint32
+}
+
+// LargeCommunitiesPathAttr is the PATH_ATTR_LARGE_COMMUNITIES path attribute
+// (RFC 8092).
+type LargeCommunitiesPathAttr struct {
+	Communities []LargeCommunity
+}
+
+// Decode decodes b, the value portion of a PATH_ATTR_LARGE_COMMUNITIES path
+// attribute, into l. A length that is not a multiple of 12 or flags other
+// than optional transitive is returned as an AttrDiscardUpdateErr.
+func (l *LargeCommunitiesPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	if len(b)%12 != 0 || !checkAttrFlags(flags, true, true) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_LARGE_COMMUNITIES, flags, b)}
+	}
+	communities := make([]LargeCommunity, 0, len(b)/12)
+	for i := 0; i < len(b); i += 12 {
+		communities = append(communities, LargeCommunity{
+			GlobalAdmin: binary.BigEndian.Uint32(b[i : i+4]),
+			LocalData1:  binary.BigEndian.Uint32(b[i+4 : i+8]),
+			LocalData2:  binary.BigEndian.Uint32(b[i+8 : i+12]),
+		})
+	}
+	l.Communities = communities
+	return nil
+}
+
+// Encode returns the wire encoding of l, including header.
+func (l LargeCommunitiesPathAttr) Encode() []byte {
+	value := make([]byte, 0, 12*len(l.Communities))
+	for _, comm := range l.Communities {
+		var cb [12]byte
+		binary.BigEndian.PutUint32(cb[0:4], comm.GlobalAdmin)
+		binary.BigEndian.PutUint32(cb[4:8], comm.LocalData1)
+		binary.BigEndian.PutUint32(cb[8:12], comm.LocalData2)
+		value = append(value, cb[:]...)
+	}
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_LARGE_COMMUNITIES, value)
+}
+
+// Extended community types/sub-types supported by ExtendedCommunity, per
+// RFC 4360 and RFC 5701.
+const (
+	EXT_COMMUNITY_TYPE_TWO_OCTET_AS  = 0x00
+	EXT_COMMUNITY_TYPE_IPV4_ADDR     = 0x01
+	EXT_COMMUNITY_TYPE_FOUR_OCTET_AS = 0x02
+	EXT_COMMUNITY_TYPE_OPAQUE        = 0x03
+)
+
+// ExtendedCommunity is a single RFC 4360/RFC 5701 extended community. Type
+// is the first octet with the transitive bit (0x40) cleared; Subtype is the
+// second octet. Value holds the remaining 6 octets for a community decoded
+// by Decode or 18 octets (RFC 5701) for one decoded by DecodeIPv6.
+type ExtendedCommunity struct {
+	Transitive bool
+	Type       uint8
+	Subtype    uint8
+	Value      []byte
+}
+
+// ExtendedCommunitiesPathAttr is the PATH_ATTR_EXTENDED_COMMUNITIES path
+// attribute (RFC 4360). Each community is 8 octets, except when the
+// attribute is carried for an IPv6 address family peer per RFC 5701, in
+// which IPv6-address-specific communities are 20 octets; DecodeIPv6 should
+// be used in that case instead of Decode.
+type ExtendedCommunitiesPathAttr struct {
+	Communities []ExtendedCommunity
+}
+
+func decodeExtendedCommunities(b []byte, width int) ([]ExtendedCommunity, error) {
+	if len(b)%width != 0 {
+		return nil, fmt.Errorf("corebgp: extended community value length %d is not a multiple of %d", len(b), width)
+	}
+	communities := make([]ExtendedCommunity, 0, len(b)/width)
+	for i := 0; i < len(b); i += width {
+		typeOctet := b[i]
+		communities = append(communities, ExtendedCommunity{
+			Transitive: typeOctet&0x40 == 0,
+			Type:       typeOctet &^ 0x40,
+			Subtype:    b[i+1],
+			Value:      b[i+2 : i+width],
+		})
+	}
+	return communities, nil
+}
+
+// Decode decodes b, the value portion of a PATH_ATTR_EXTENDED_COMMUNITIES
+// path attribute carrying 8-octet (two-octet-AS, IPv4-address, or opaque)
+// communities, into e. A length that is not a multiple of 8 or flags other
+// than optional transitive is returned as an AttrDiscardUpdateErr.
+func (e *ExtendedCommunitiesPathAttr) Decode(flags PathAttrFlags, b []byte) error {
+	communities, err := decodeExtendedCommunities(b, 8)
+	if err != nil || !checkAttrFlags(flags, true, true) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_EXTENDED_COMMUNITIES, flags, b)}
+	}
+	e.Communities = communities
+	return nil
+}
+
+// DecodeIPv6 decodes b as RFC 5701 IPv6 address specific extended
+// communities, which are 20 octets wide rather than the usual 8. A length
+// that is not a multiple of 20 or flags other than optional transitive is
+// returned as an AttrDiscardUpdateErr.
+func (e *ExtendedCommunitiesPathAttr) DecodeIPv6(flags PathAttrFlags, b []byte) error {
+	communities, err := decodeExtendedCommunities(b, 20)
+	if err != nil || !checkAttrFlags(flags, true, true) {
+		return &AttrDiscardUpdateErr{Notification: newAttrLenNotification(PATH_ATTR_EXTENDED_COMMUNITIES, flags, b)}
+	}
+	e.Communities = communities
+	return nil
+}
+
+// Encode returns the wire encoding of e, including header.
+func (e ExtendedCommunitiesPathAttr) Encode() []byte {
+	var value []byte
+	for _, comm := range e.Communities {
+		typeOctet := comm.Type
+		if !comm.Transitive {
+			typeOctet |= 0x40
+		}
+		value = append(value, typeOctet, comm.Subtype)
+		value = append(value, comm.Value...)
+	}
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_EXTENDED_COMMUNITIES, value)
+}
+
+// checkAttrFlags reports whether flags carries exactly the optional and
+// transitive bits a path attribute's RFC definition requires. A mismatch
+// here is an Attribute Flags Error (RFC 4271 section 6.3); per RFC 7606
+// this package handles it the same as an invalid-length value for the same
+// attribute, so callers check it alongside length.
+func checkAttrFlags(flags PathAttrFlags, wantOptional, wantTransitive bool) bool {
+	gotOptional := flags&PATH_ATTR_FLAG_OPTIONAL != 0
+	gotTransitive := flags&PATH_ATTR_FLAG_TRANSITIVE != 0
+	return gotOptional == wantOptional && gotTransitive == wantTransitive
+}
+
+// newAttrLenNotification builds the Notification carried by
+// AttrDiscardUpdateErr or TreatAsWithdrawUpdateErr for a path attribute
+// that failed to decode due to an invalid length or invalid flags, mirroring the ATTR_LEN_ERR handling already used for the
+// well-known mandatory attributes.
+func newAttrLenNotification(code uint8, flags PathAttrFlags, b []byte) *Notification {
+	data := append([]byte{byte(flags), code}, b...)
+	return &Notification{
+		Code:    NOTIF_CODE_UPDATE_MSG_ERR,
+		Subcode: NOTIF_SUBCODE_ATTR_LEN_ERR,
+		Data:    data,
+	}
+}
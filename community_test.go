@@ -0,0 +1,116 @@
+package corebgp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCommunitiesPathAttr_Decode(t *testing.T) {
+	cases := []struct {
+		name    string
+		b       []byte
+		want    []Community
+		wantErr bool
+	}{
+		{
+			name: "two communities",
+			b:    []byte{0xff, 0xff, 0x00, 0x01, 0x00, 0x01, 0x00, 0x02},
+			want: []Community{0xffff0001, 0x00010002},
+		},
+		{
+			name:    "invalid length",
+			b:       []byte{0x00, 0x01},
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var c CommunitiesPathAttr
+			err := c.Decode(PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, tt.b)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Decode() returned nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() err = %v", err)
+			}
+			if !reflect.DeepEqual(tt.want, c.Communities) {
+				t.Fatalf("want: %+v != got: %+v", tt.want, c.Communities)
+			}
+		})
+	}
+}
+
+func TestLargeCommunitiesPathAttr_Decode(t *testing.T) {
+	b := []byte{
+		0x00, 0x00, 0xfd, 0xea, // global admin 65002
+		0x00, 0x00, 0x00, 0x01, // local data 1
+		0x00, 0x00, 0x00, 0x02, // local data 2
+	}
+	var l LargeCommunitiesPathAttr
+	if err := l.Decode(PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, b); err != nil {
+		t.Fatalf("Decode() err = %v", err)
+	}
+	want := []LargeCommunity{{GlobalAdmin: 65002, LocalData1: 1, LocalData2: 2}}
+	if !reflect.DeepEqual(want, l.Communities) {
+		t.Fatalf("want: %+v != got: %+v", want, l.Communities)
+	}
+}
+
+func TestExtendedCommunitiesPathAttr_Decode(t *testing.T) {
+	b := []byte{
+		0x00, 0x02, 0xfd, 0xea, 0x00, 0x00, 0x00, 0x01, // transitive two-octet-AS: 65002:1
+	}
+	var e ExtendedCommunitiesPathAttr
+	if err := e.Decode(PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, b); err != nil {
+		t.Fatalf("Decode() err = %v", err)
+	}
+	want := []ExtendedCommunity{
+		{
+			Transitive: true,
+			Type:       EXT_COMMUNITY_TYPE_TWO_OCTET_AS,
+			Subtype:    0x02,
+			Value:      []byte{0xfd, 0xea, 0x00, 0x00, 0x00, 0x01},
+		},
+	}
+	if !reflect.DeepEqual(want, e.Communities) {
+		t.Fatalf("want: %+v != got: %+v", want, e.Communities)
+	}
+}
+
+func TestMultiExitDiscPathAttr_Decode(t *testing.T) {
+	var m MultiExitDiscPathAttr
+	if err := m.Decode(PATH_ATTR_FLAG_OPTIONAL, []byte{0x00, 0x00, 0x00, 0x64}); err != nil {
+		t.Fatalf("Decode() err = %v", err)
+	}
+	if m != 100 {
+		t.Fatalf("want 100, got %d", m)
+	}
+}
+
+func TestMultiExitDiscPathAttr_Decode_InvalidFlags(t *testing.T) {
+	var m MultiExitDiscPathAttr
+	// MED must be optional non-transitive; transitive here is invalid.
+	err := m.Decode(PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, []byte{0x00, 0x00, 0x00, 0x64})
+	var asWithdraw *TreatAsWithdrawUpdateErr
+	if !errors.As(err, &asWithdraw) {
+		t.Fatalf("want TreatAsWithdrawUpdateErr, got %v", err)
+	}
+}
+
+func TestAggregatorPathAttr_Decode(t *testing.T) {
+	b := []byte{0xfd, 0xea, 0xc0, 0x00, 0x02, 0x02} // AS 65002, speaker 192.0.2.2
+	var a AggregatorPathAttr
+	if err := a.Decode(PATH_ATTR_FLAG_OPTIONAL|PATH_ATTR_FLAG_TRANSITIVE, b); err != nil {
+		t.Fatalf("Decode() err = %v", err)
+	}
+	if a.AS != 65002 {
+		t.Fatalf("want AS 65002, got %d", a.AS)
+	}
+	if a.Speaker.String() != "192.0.2.2" {
+		t.Fatalf("want speaker 192.0.2.2, got %s", a.Speaker)
+	}
+}
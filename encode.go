@@ -0,0 +1,282 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// MaxUpdateMessageLen is the largest size in bytes that an UPDATE message
+// body (everything after the 19 byte header) produced by this package will
+// grow to before UpdateBuilder.Build splits NLRI across additional messages. It is
+// derived from the RFC 4271 maximum BGP message size of 4096 bytes.
+const MaxUpdateMessageLen = 4096 - 19
+
+// encodePathAttrHeader appends the flags/code/length header for a path
+// attribute to b, setting the extended-length bit in flags if the value is
+// longer than 255 bytes. It returns the resulting slice.
+func encodePathAttrHeader(b []byte, flags PathAttrFlags, code uint8, valueLen int) []byte {
+	if valueLen > 0xff {
+		flags |= PATH_ATTR_FLAG_EXTENDED_LENGTH
+		b = append(b, byte(flags), code)
+		var lb [2]byte
+		binary.BigEndian.PutUint16(lb[:], uint16(valueLen))
+		b = append(b, lb[:]...)
+	} else {
+		flags &^= PATH_ATTR_FLAG_EXTENDED_LENGTH
+		b = append(b, byte(flags), code, byte(valueLen))
+	}
+	return b
+}
+
+// encodePathAttr appends a complete path attribute (header + value) to b.
+func encodePathAttr(b []byte, flags PathAttrFlags, code uint8, value []byte) []byte {
+	b = encodePathAttrHeader(b, flags, code, len(value))
+	return append(b, value...)
+}
+
+// Encode returns the wire encoding of o as a PATH_ATTR_ORIGIN path
+// attribute, including header.
+func (o OriginPathAttr) Encode() []byte {
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_ORIGIN, []byte{uint8(o)})
+}
+
+// Encode returns the wire encoding of a as a PATH_ATTR_AS_PATH path
+// attribute using two-octet AS_SEQUENCE segments, including header.
+func (a ASPathAttr) Encode() []byte {
+	value := make([]byte, 0, 2+2*len(a.ASSequence))
+	value = append(value, asPathSegTypeSequence, uint8(len(a.ASSequence)))
+	for _, as := range a.ASSequence {
+		var ab [2]byte
+		binary.BigEndian.PutUint16(ab[:], uint16(as))
+		value = append(value, ab[:]...)
+	}
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_AS_PATH, value)
+}
+
+// Encode4 returns the wire encoding of a as a PATH_ATTR_AS_PATH path
+// attribute using four-octet AS_SEQUENCE segments, for use with peers that
+// negotiated four-octet AS number capability.
+func (a ASPathAttr) Encode4() []byte {
+	value := make([]byte, 0, 2+4*len(a.ASSequence))
+	value = append(value, asPathSegTypeSequence, uint8(len(a.ASSequence)))
+	for _, as := range a.ASSequence {
+		var ab [4]byte
+		binary.BigEndian.PutUint32(ab[:], as)
+		value = append(value, ab[:]...)
+	}
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_AS_PATH, value)
+}
+
+// asPathSegTypeSequence is the AS_PATH segment type for AS_SEQUENCE as
+// defined in RFC 4271 section 4.3.
+const asPathSegTypeSequence = 2
+
+// Encode returns the wire encoding of nh as a PATH_ATTR_NEXT_HOP path
+// attribute, including header. nh must be an IPv4 address.
+func (nh NextHopPathAttr) Encode() ([]byte, error) {
+	a := netip.Addr(nh)
+	if !a.Is4() {
+		return nil, fmt.Errorf("corebgp: NextHopPathAttr.Encode: %s is not an IPv4 address", a)
+	}
+	ab := a.As4()
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_NEXT_HOP, ab[:]), nil
+}
+
+// Encode returns the wire encoding of the PATH_ATTR_ATOMIC_AGGREGATE path
+// attribute, including header. The attribute has no value.
+func (aa AtomicAggregatePathAttr) Encode() []byte {
+	return encodePathAttr(nil, PATH_ATTR_FLAG_TRANSITIVE, PATH_ATTR_ATOMIC_AGGREGATE, nil)
+}
+
+// EncodeMPIPv6Prefixes encodes prefixes as a series of <length, prefix>
+// tuples suitable for use as the NLRI portion of a MP_REACH_NLRI or
+// MP_UNREACH_NLRI path attribute for AFI_IPV6. It is the encoding
+// counterpart of DecodeMPIPv6Prefixes.
+func EncodeMPIPv6Prefixes(prefixes []netip.Prefix) ([]byte, error) {
+	var b []byte
+	for _, p := range prefixes {
+		if !p.Addr().Is6() {
+			return nil, fmt.Errorf("corebgp: EncodeMPIPv6Prefixes: %s is not an IPv6 prefix", p)
+		}
+		bitLen := p.Bits()
+		byteLen := (bitLen + 7) / 8
+		b = append(b, uint8(bitLen))
+		addrBytes := p.Addr().As16()
+		b = append(b, addrBytes[:byteLen]...)
+	}
+	return b, nil
+}
+
+// EncodeMPIPv6NextHops encodes nhs as the next hop portion of a
+// MP_REACH_NLRI path attribute for AFI_IPV6, preceded by its length octet.
+// It is the encoding counterpart of DecodeMPReachIPv6NextHops.
+func EncodeMPIPv6NextHops(nhs []netip.Addr) ([]byte, error) {
+	b := make([]byte, 0, 1+16*len(nhs))
+	for _, nh := range nhs {
+		if !nh.Is6() {
+			return nil, fmt.Errorf("corebgp: EncodeMPIPv6NextHops: %s is not an IPv6 address", nh)
+		}
+		ab := nh.As16()
+		b = append(b, ab[:]...)
+	}
+	return append([]byte{uint8(len(b))}, b...), nil
+}
+
+// EncodeMPReachNLRI encodes a PATH_ATTR_MP_REACH_NLRI path attribute for the
+// given afi/safi, including header. nextHops and nlri must already be
+// encoded for the given afi/safi, e.g. via EncodeMPIPv6NextHops and
+// EncodeMPIPv6Prefixes.
+func EncodeMPReachNLRI(afi uint16, safi uint8, nextHops, nlri []byte) []byte {
+	value := make([]byte, 0, 3+len(nextHops)+1+len(nlri))
+	var afib [2]byte
+	binary.BigEndian.PutUint16(afib[:], afi)
+	value = append(value, afib[:]...)
+	value = append(value, safi)
+	value = append(value, nextHops...)
+	value = append(value, 0) // reserved
+	value = append(value, nlri...)
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_MP_REACH_NLRI, value)
+}
+
+// EncodeMPUnreachNLRI encodes a PATH_ATTR_MP_UNREACH_NLRI path attribute for
+// the given afi/safi, including header. withdrawn must already be encoded
+// for the given afi/safi, e.g. via EncodeMPIPv6Prefixes. A nil/empty
+// withdrawn encodes an End-of-RIB marker for afi/safi.
+func EncodeMPUnreachNLRI(afi uint16, safi uint8, withdrawn []byte) []byte {
+	value := make([]byte, 0, 3+len(withdrawn))
+	var afib [2]byte
+	binary.BigEndian.PutUint16(afib[:], afi)
+	value = append(value, afib[:]...)
+	value = append(value, safi)
+	value = append(value, withdrawn...)
+	return encodePathAttr(nil, PATH_ATTR_FLAG_OPTIONAL, PATH_ATTR_MP_UNREACH_NLRI, value)
+}
+
+// encodeIPv4Prefixes encodes prefixes as a series of <length, prefix> tuples
+// suitable for use as the NLRI or withdrawn routes portion of an UPDATE
+// message, per RFC 4271 section 4.3.
+func encodeIPv4Prefixes(prefixes []netip.Prefix) ([]byte, error) {
+	var b []byte
+	for _, p := range prefixes {
+		if !p.Addr().Is4() {
+			return nil, fmt.Errorf("corebgp: encodeIPv4Prefixes: %s is not an IPv4 prefix", p)
+		}
+		bitLen := p.Bits()
+		byteLen := (bitLen + 7) / 8
+		b = append(b, uint8(bitLen))
+		addrBytes := p.Addr().As4()
+		b = append(b, addrBytes[:byteLen]...)
+	}
+	return b, nil
+}
+
+// UpdateBuilder accumulates IPv4 withdrawn routes, IPv4 NLRI, and arbitrary
+// encoded path attributes (e.g. from EncodeMPReachNLRI/EncodeMPUnreachNLRI)
+// and produces one or more UPDATE message bodies via Build, splitting NLRI
+// across additional messages as needed to respect MaxUpdateMessageLen. It is
+// the builder counterpart of UpdateDecoder, intended for callers that need
+// to originate or withdraw routes, e.g. advertising anycast VIPs to a route
+// reflector without implementing a full BGP speaker.
+type UpdateBuilder struct {
+	withdrawn []netip.Prefix
+	nlri      []netip.Prefix
+	pathAttrs []byte
+}
+
+// NewUpdateBuilder returns an empty UpdateBuilder.
+func NewUpdateBuilder() *UpdateBuilder {
+	return &UpdateBuilder{}
+}
+
+// AddWithdrawn adds IPv4 prefixes to the set of withdrawn routes.
+func (b *UpdateBuilder) AddWithdrawn(prefixes ...netip.Prefix) *UpdateBuilder {
+	b.withdrawn = append(b.withdrawn, prefixes...)
+	return b
+}
+
+// AddNLRI adds IPv4 prefixes to the set of reachable NLRI.
+func (b *UpdateBuilder) AddNLRI(prefixes ...netip.Prefix) *UpdateBuilder {
+	b.nlri = append(b.nlri, prefixes...)
+	return b
+}
+
+// AddPathAttr appends an already-encoded path attribute, e.g. the output of
+// OriginPathAttr.Encode, ASPathAttr.Encode, NextHopPathAttr.Encode,
+// EncodeMPReachNLRI, or EncodeMPUnreachNLRI.
+func (b *UpdateBuilder) AddPathAttr(encoded []byte) *UpdateBuilder {
+	b.pathAttrs = append(b.pathAttrs, encoded...)
+	return b
+}
+
+// Build encodes the accumulated withdrawn routes, path attributes, and NLRI
+// into one or more UPDATE message bodies (not including the 19 byte BGP
+// header), splitting b.nlri across multiple messages if the first message
+// would exceed MaxUpdateMessageLen. Each returned message carries the full
+// set of withdrawn routes and path attributes alongside a subset of the
+// NLRI; callers with independently-sized withdrawn routes should use
+// EncodeUpdate directly instead.
+func (b *UpdateBuilder) Build() ([][]byte, error) {
+	withdrawn, err := encodeIPv4Prefixes(b.withdrawn)
+	if err != nil {
+		return nil, err
+	}
+	if len(withdrawn)+2+len(b.pathAttrs)+2 > MaxUpdateMessageLen {
+		return nil, fmt.Errorf("corebgp: UpdateBuilder.Build: withdrawn routes and path attributes alone exceed %d bytes", MaxUpdateMessageLen)
+	}
+	fixedLen := 2 + len(withdrawn) + 2 + len(b.pathAttrs)
+	budget := MaxUpdateMessageLen - fixedLen
+	var msgs [][]byte
+	remaining := b.nlri
+	for {
+		var (
+			taken []netip.Prefix
+			used  int
+		)
+		for len(remaining) > 0 {
+			enc, err := encodeIPv4Prefixes(remaining[:1])
+			if err != nil {
+				return nil, err
+			}
+			if used+len(enc) > budget && len(taken) > 0 {
+				break
+			}
+			taken = append(taken, remaining[0])
+			used += len(enc)
+			remaining = remaining[1:]
+		}
+		nlri, err := encodeIPv4Prefixes(taken)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, EncodeUpdate(withdrawn, b.pathAttrs, nlri))
+		if len(remaining) == 0 {
+			break
+		}
+	}
+	if len(msgs) == 0 {
+		// no NLRI; a single message carrying only withdrawn routes and/or
+		// path attributes is still valid (e.g. a pure withdraw).
+		msgs = append(msgs, EncodeUpdate(withdrawn, b.pathAttrs, nil))
+	}
+	return msgs, nil
+}
+
+// EncodeUpdate assembles an UPDATE message body (not including the 19 byte
+// BGP header) from already-encoded withdrawn routes, path attributes, and
+// NLRI, e.g. from encodeIPv4Prefixes/EncodeMPIPv6Prefixes and
+// OriginPathAttr.Encode/EncodeMPReachNLRI/EncodeMPUnreachNLRI. It performs no
+// validation of the MaxUpdateMessageLen limit; callers packing an unbounded
+// number of prefixes should use UpdateBuilder instead.
+func EncodeUpdate(withdrawn, pathAttrs, nlri []byte) []byte {
+	b := make([]byte, 0, 2+len(withdrawn)+2+len(pathAttrs)+len(nlri))
+	var lb [2]byte
+	binary.BigEndian.PutUint16(lb[:], uint16(len(withdrawn)))
+	b = append(b, lb[:]...)
+	b = append(b, withdrawn...)
+	binary.BigEndian.PutUint16(lb[:], uint16(len(pathAttrs)))
+	b = append(b, lb[:]...)
+	b = append(b, pathAttrs...)
+	b = append(b, nlri...)
+	return b
+}
@@ -0,0 +1,80 @@
+package corebgp
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateBuilder_Build_RoundTrip(t *testing.T) {
+	origin := OriginPathAttr(0)
+	asPath := ASPathAttr{ASSequence: []uint32{65002}}
+	nh, err := NextHopPathAttr(netip.MustParseAddr("192.0.2.2")).Encode()
+	if err != nil {
+		t.Fatalf("NextHopPathAttr.Encode() err = %v", err)
+	}
+
+	b := NewUpdateBuilder().
+		AddNLRI(netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.1.0.0/16")).
+		AddPathAttr(origin.Encode()).
+		AddPathAttr(asPath.Encode4()).
+		AddPathAttr(nh)
+
+	msgs, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() err = %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Build() got %d messages, want 1", len(msgs))
+	}
+
+	ud := NewUpdateDecoder[*updateMessageForTests](
+		NewWithdrawnRoutesDecodeFn(func(m *updateMessageForTests, r []netip.Prefix) error {
+			m.withdrawn = r
+			return nil
+		}),
+		newPathAttrsDecodeFn(),
+		NewNLRIDecodeFn(func(m *updateMessageForTests, r []netip.Prefix) error {
+			m.nlri = r
+			return nil
+		}),
+	)
+	m := &updateMessageForTests{}
+	if err := ud.Decode(m, msgs[0]); err != nil {
+		t.Fatalf("Decode() err = %v", err)
+	}
+	want := &updateMessageForTests{
+		origin:  0,
+		asPath:  []uint32{65002},
+		nextHop: netip.MustParseAddr("192.0.2.2"),
+		nlri: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("10.1.0.0/16"),
+		},
+	}
+	if !reflect.DeepEqual(want, m) {
+		t.Fatalf("want: %+v != got: %+v", want, m)
+	}
+}
+
+func TestEncodeMPReachNLRI_IPv6_RoundTrip(t *testing.T) {
+	nlri, err := EncodeMPIPv6Prefixes([]netip.Prefix{netip.MustParsePrefix("2001:db8::/64")})
+	if err != nil {
+		t.Fatalf("EncodeMPIPv6Prefixes() err = %v", err)
+	}
+	nh, err := EncodeMPIPv6NextHops([]netip.Addr{netip.MustParseAddr("2001:db8::2")})
+	if err != nil {
+		t.Fatalf("EncodeMPIPv6NextHops() err = %v", err)
+	}
+	attr := EncodeMPReachNLRI(AFI_IPV6, SAFI_UNICAST, nh, nlri)
+
+	var m updateMessageForTests
+	fn := newPathAttrsDecodeFn()
+	if err := fn(&m, PATH_ATTR_MP_REACH_NLRI, PathAttrFlags(attr[0]), attr[3:]); err != nil {
+		t.Fatalf("decode of encoded MP_REACH_NLRI err = %v", err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("2001:db8::/64")}
+	if !reflect.DeepEqual(want, m.ipv6NLRI) {
+		t.Fatalf("want: %+v != got: %+v", want, m.ipv6NLRI)
+	}
+}
@@ -0,0 +1,297 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// EVPN route types (RFC 7432), carried as the first octet of an AFI
+// 25/SAFI 70 NLRI entry.
+const (
+	EVPN_ROUTE_TYPE_ETHERNET_AUTO_DISCOVERY = 1
+	EVPN_ROUTE_TYPE_MAC_IP_ADVERTISEMENT    = 2
+	EVPN_ROUTE_TYPE_INCLUSIVE_MULTICAST     = 3
+	EVPN_ROUTE_TYPE_ETHERNET_SEGMENT        = 4
+	EVPN_ROUTE_TYPE_IP_PREFIX               = 5
+)
+
+// ESI is a 10-octet RFC 7432 Ethernet Segment Identifier.
+type ESI [10]byte
+
+// EVPNRoute is a single decoded EVPN NLRI entry. Type indicates which of
+// the Fields is populated; exactly one of EthernetAutoDiscovery,
+// MACIPAdvertisement, InclusiveMulticast, EthernetSegment, or IPPrefix is
+// non-nil, matching Type.
+type EVPNRoute struct {
+	Type uint8
+
+	EthernetAutoDiscovery *EVPNEthernetAutoDiscoveryRoute
+	MACIPAdvertisement    *EVPNMACIPAdvertisementRoute
+	InclusiveMulticast    *EVPNInclusiveMulticastRoute
+	EthernetSegment       *EVPNEthernetSegmentRoute
+	IPPrefix              *EVPNIPPrefixRoute
+}
+
+// EVPNEthernetAutoDiscoveryRoute is EVPN route type 1.
+type EVPNEthernetAutoDiscoveryRoute struct {
+	RD          RouteDistinguisher
+	ESI         ESI
+	EthernetTag uint32
+	Label       uint32
+}
+
+// EVPNMACIPAdvertisementRoute is EVPN route type 2.
+type EVPNMACIPAdvertisementRoute struct {
+	RD          RouteDistinguisher
+	ESI         ESI
+	EthernetTag uint32
+	MAC         [6]byte
+	IP          netip.Addr // zero Addr if no IP was advertised
+	Labels      []uint32
+}
+
+// EVPNInclusiveMulticastRoute is EVPN route type 3 (Inclusive Multicast
+// Ethernet Tag route).
+type EVPNInclusiveMulticastRoute struct {
+	RD             RouteDistinguisher
+	EthernetTag    uint32
+	OriginatingRtr netip.Addr
+}
+
+// EVPNEthernetSegmentRoute is EVPN route type 4.
+type EVPNEthernetSegmentRoute struct {
+	RD             RouteDistinguisher
+	ESI            ESI
+	OriginatingRtr netip.Addr
+}
+
+// EVPNIPPrefixRoute is EVPN route type 5.
+type EVPNIPPrefixRoute struct {
+	RD          RouteDistinguisher
+	ESI         ESI
+	EthernetTag uint32
+	Prefix      netip.Prefix
+	GatewayIP   netip.Addr
+	Label       uint32
+}
+
+// DecodeMPEVPNNLRI decodes b as a series of RFC 7432 EVPN NLRI entries for
+// AFI_L2VPN/SAFI_EVPN, each prefixed by a 1-octet route type and 1-octet
+// length.
+func DecodeMPEVPNNLRI(b []byte) ([]EVPNRoute, error) {
+	var routes []EVPNRoute
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, fmt.Errorf("corebgp: truncated EVPN NLRI")
+		}
+		routeType := b[0]
+		length := int(b[1])
+		if len(b) < 2+length {
+			return nil, fmt.Errorf("corebgp: truncated EVPN NLRI")
+		}
+		value := b[2 : 2+length]
+		route, err := decodeEVPNRoute(routeType, value)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+		b = b[2+length:]
+	}
+	return routes, nil
+}
+
+func decodeEVPNRoute(routeType uint8, value []byte) (EVPNRoute, error) {
+	switch routeType {
+	case EVPN_ROUTE_TYPE_ETHERNET_AUTO_DISCOVERY:
+		if len(value) != 25 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: EVPN type 1 route length %d != 25", len(value))
+		}
+		rd, err := decodeRouteDistinguisher(value)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		var esi ESI
+		copy(esi[:], value[8:18])
+		return EVPNRoute{
+			Type: routeType,
+			EthernetAutoDiscovery: &EVPNEthernetAutoDiscoveryRoute{
+				RD:          rd,
+				ESI:         esi,
+				EthernetTag: binary.BigEndian.Uint32(value[18:22]),
+				Label:       decodeSingleMPLSLabel(value[22:25]),
+			},
+		}, nil
+	case EVPN_ROUTE_TYPE_MAC_IP_ADVERTISEMENT:
+		// RD(8) + ESI(10) + ethernet tag(4) + MAC len(1) + MAC(6) + IP
+		// len(1) octets are always present; the MAC/IP length octets
+		// themselves are read before the IP address and label(s).
+		if len(value) < 30 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 2 route")
+		}
+		rd, err := decodeRouteDistinguisher(value)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		var esi ESI
+		copy(esi[:], value[8:18])
+		ethTag := binary.BigEndian.Uint32(value[18:22])
+		macLen := value[22]
+		if macLen != 48 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: unsupported EVPN MAC address length %d", macLen)
+		}
+		var mac [6]byte
+		copy(mac[:], value[23:29])
+		i := 29
+		if len(value) < i+1 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 2 route")
+		}
+		ipLen := value[i]
+		i++
+		var ip netip.Addr
+		switch ipLen {
+		case 0:
+		case 32:
+			if len(value) < i+4 {
+				return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 2 route")
+			}
+			ip = netip.AddrFrom4([4]byte(value[i : i+4]))
+			i += 4
+		case 128:
+			if len(value) < i+16 {
+				return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 2 route")
+			}
+			ip = netip.AddrFrom16([16]byte(value[i : i+16]))
+			i += 16
+		default:
+			return EVPNRoute{}, fmt.Errorf("corebgp: unsupported EVPN IP address length %d", ipLen)
+		}
+		var labels []uint32
+		for len(value) >= i+3 {
+			labels = append(labels, decodeSingleMPLSLabel(value[i:i+3]))
+			i += 3
+		}
+		return EVPNRoute{
+			Type: routeType,
+			MACIPAdvertisement: &EVPNMACIPAdvertisementRoute{
+				RD:          rd,
+				ESI:         esi,
+				EthernetTag: ethTag,
+				MAC:         mac,
+				IP:          ip,
+				Labels:      labels,
+			},
+		}, nil
+	case EVPN_ROUTE_TYPE_INCLUSIVE_MULTICAST:
+		if len(value) < 13 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 3 route")
+		}
+		rd, err := decodeRouteDistinguisher(value)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		ethTag := binary.BigEndian.Uint32(value[8:12])
+		ipLen := value[12]
+		rtr, err := decodeEVPNIPAddr(value[13:], ipLen)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		return EVPNRoute{
+			Type: routeType,
+			InclusiveMulticast: &EVPNInclusiveMulticastRoute{
+				RD:             rd,
+				EthernetTag:    ethTag,
+				OriginatingRtr: rtr,
+			},
+		}, nil
+	case EVPN_ROUTE_TYPE_ETHERNET_SEGMENT:
+		if len(value) < 19 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 4 route")
+		}
+		rd, err := decodeRouteDistinguisher(value)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		var esi ESI
+		copy(esi[:], value[8:18])
+		ipLen := value[18]
+		rtr, err := decodeEVPNIPAddr(value[19:], ipLen)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		return EVPNRoute{
+			Type: routeType,
+			EthernetSegment: &EVPNEthernetSegmentRoute{
+				RD:             rd,
+				ESI:            esi,
+				OriginatingRtr: rtr,
+			},
+		}, nil
+	case EVPN_ROUTE_TYPE_IP_PREFIX:
+		if len(value) < 34 {
+			return EVPNRoute{}, fmt.Errorf("corebgp: truncated EVPN type 5 route")
+		}
+		rd, err := decodeRouteDistinguisher(value)
+		if err != nil {
+			return EVPNRoute{}, err
+		}
+		var esi ESI
+		copy(esi[:], value[8:18])
+		ethTag := binary.BigEndian.Uint32(value[18:22])
+		prefixBits := int(value[22])
+		var prefix netip.Prefix
+		var gw netip.Addr
+		var label uint32
+		if len(value) == 22+1+4+4+3 {
+			// IPv4 prefix: 4B prefix, 4B gateway, 3B label
+			prefix = netip.PrefixFrom(netip.AddrFrom4([4]byte(value[23:27])), prefixBits)
+			gw = netip.AddrFrom4([4]byte(value[27:31]))
+			label = decodeSingleMPLSLabel(value[31:34])
+		} else if len(value) == 22+1+16+16+3 {
+			// IPv6 prefix: 16B prefix, 16B gateway, 3B label
+			prefix = netip.PrefixFrom(netip.AddrFrom16([16]byte(value[23:39])), prefixBits)
+			gw = netip.AddrFrom16([16]byte(value[39:55]))
+			label = decodeSingleMPLSLabel(value[55:58])
+		} else {
+			return EVPNRoute{}, fmt.Errorf("corebgp: unsupported EVPN type 5 route length %d", len(value))
+		}
+		return EVPNRoute{
+			Type: routeType,
+			IPPrefix: &EVPNIPPrefixRoute{
+				RD:          rd,
+				ESI:         esi,
+				EthernetTag: ethTag,
+				Prefix:      prefix,
+				GatewayIP:   gw,
+				Label:       label,
+			},
+		}, nil
+	default:
+		return EVPNRoute{}, fmt.Errorf("corebgp: unsupported EVPN route type %d", routeType)
+	}
+}
+
+func decodeEVPNIPAddr(b []byte, length uint8) (netip.Addr, error) {
+	switch length {
+	case 0:
+		return netip.Addr{}, nil
+	case 32:
+		if len(b) < 4 {
+			return netip.Addr{}, fmt.Errorf("corebgp: truncated EVPN IPv4 address")
+		}
+		return netip.AddrFrom4([4]byte(b[:4])), nil
+	case 128:
+		if len(b) < 16 {
+			return netip.Addr{}, fmt.Errorf("corebgp: truncated EVPN IPv6 address")
+		}
+		return netip.AddrFrom16([16]byte(b[:16])), nil
+	default:
+		return netip.Addr{}, fmt.Errorf("corebgp: unsupported EVPN address length %d", length)
+	}
+}
+
+// decodeSingleMPLSLabel decodes a single 3-octet MPLS label, ignoring the
+// bottom-of-stack bit (each EVPN label field is decoded on its own).
+func decodeSingleMPLSLabel(b []byte) uint32 {
+	return uint32(b[0])<<12 | uint32(b[1])<<4 | uint32(b[2])>>4
+}
@@ -0,0 +1,211 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// Flowspec component types (RFC 8955 section 4, RFC 8956 for IPv6), the
+// first octet of each TLV within a Flowspec NLRI.
+const (
+	FLOWSPEC_COMPONENT_DST_PREFIX    = 1
+	FLOWSPEC_COMPONENT_SRC_PREFIX    = 2
+	FLOWSPEC_COMPONENT_IP_PROTOCOL   = 3
+	FLOWSPEC_COMPONENT_PORT          = 4
+	FLOWSPEC_COMPONENT_DST_PORT      = 5
+	FLOWSPEC_COMPONENT_SRC_PORT      = 6
+	FLOWSPEC_COMPONENT_ICMP_TYPE     = 7
+	FLOWSPEC_COMPONENT_ICMP_CODE     = 8
+	FLOWSPEC_COMPONENT_TCP_FLAGS     = 9
+	FLOWSPEC_COMPONENT_PACKET_LENGTH = 10
+	FLOWSPEC_COMPONENT_DSCP          = 11
+	FLOWSPEC_COMPONENT_FRAGMENT      = 12
+)
+
+// Numeric operator flag bits shared by the operator octet that precedes
+// each value in an RFC 8955 numeric-op component (port, ICMP type/code,
+// packet length, DSCP).
+const (
+	FLOWSPEC_NUMERIC_OP_END   = 1 << 7
+	FLOWSPEC_NUMERIC_OP_AND   = 1 << 6
+	FLOWSPEC_NUMERIC_OP_LT    = 1 << 2
+	FLOWSPEC_NUMERIC_OP_GT    = 1 << 1
+	FLOWSPEC_NUMERIC_OP_EQ    = 1 << 0
+	flowspecNumericOpLenShift = 4
+)
+
+// FlowspecNumericValue is a single <op, value> entry of a numeric-op
+// Flowspec component, e.g. one clause of "port = 80 or port = 443".
+type FlowspecNumericValue struct {
+	Op    uint8
+	Value uint64
+}
+
+// FlowspecPrefixComponent is a destination or source prefix component
+// (type 1/2). For an RFC 8956 IPv6 component, Offset is the number of
+// leading prefix bits not carried in the pattern; those bits are zero in
+// Prefix. Offset is always 0 for an RFC 8955 IPv4 component.
+type FlowspecPrefixComponent struct {
+	Type   uint8
+	Prefix netip.Prefix
+	Offset uint8
+}
+
+// FlowspecComponent is a single decoded TLV within a Flowspec NLRI. Type
+// indicates which of Prefix or Numeric is populated; IP_PROTOCOL, PORT,
+// DST_PORT, SRC_PORT, ICMP_TYPE, ICMP_CODE, TCP_FLAGS, PACKET_LENGTH, DSCP,
+// and FRAGMENT all decode as Numeric (TCP_FLAGS/FRAGMENT reuse the
+// numeric-op encoding as a bitmask match per RFC 8955 section 4.2.1).
+type FlowspecComponent struct {
+	Type    uint8
+	Prefix  *FlowspecPrefixComponent
+	Numeric []FlowspecNumericValue
+}
+
+// FlowspecRule is a single decoded Flowspec NLRI entry: an ordered set of
+// components that are ANDed together, per RFC 8955 section 4.
+type FlowspecRule struct {
+	Components []FlowspecComponent
+}
+
+// DecodeMPFlowspecNLRI decodes b as a series of RFC 8955/RFC 8956 Flowspec
+// NLRI entries for SAFI_FLOWSPEC, each prefixed by a 1- or 2-octet NLRI
+// length depending on whether the high bits of the first octet indicate an
+// extended length per RFC 8955 section 4.
+func DecodeMPFlowspecNLRI(b []byte, addrBits int) ([]FlowspecRule, error) {
+	var rules []FlowspecRule
+	for len(b) > 0 {
+		length, lenLen, err := decodeFlowspecNLRILength(b)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < lenLen+length {
+			return nil, fmt.Errorf("corebgp: truncated flowspec NLRI")
+		}
+		value := b[lenLen : lenLen+length]
+		components, err := decodeFlowspecComponents(value, addrBits)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, FlowspecRule{Components: components})
+		b = b[lenLen+length:]
+	}
+	return rules, nil
+}
+
+func decodeFlowspecNLRILength(b []byte) (length, consumed int, err error) {
+	if len(b) < 1 {
+		return 0, 0, fmt.Errorf("corebgp: truncated flowspec NLRI length")
+	}
+	if b[0] >= 0xf0 {
+		if len(b) < 2 {
+			return 0, 0, fmt.Errorf("corebgp: truncated flowspec NLRI length")
+		}
+		return int(binary.BigEndian.Uint16(b[0:2]) &^ 0xf000), 2, nil
+	}
+	return int(b[0]), 1, nil
+}
+
+func decodeFlowspecComponents(b []byte, addrBits int) ([]FlowspecComponent, error) {
+	var components []FlowspecComponent
+	for len(b) > 0 {
+		compType := b[0]
+		b = b[1:]
+		switch compType {
+		case FLOWSPEC_COMPONENT_DST_PREFIX, FLOWSPEC_COMPONENT_SRC_PREFIX:
+			if len(b) < 1 {
+				return nil, fmt.Errorf("corebgp: truncated flowspec prefix component")
+			}
+			prefixBits := int(b[0])
+			b = b[1:]
+			// RFC 8956 IPv6 prefix components carry a 1-octet offset
+			// before the pattern, absent from the RFC 8955 IPv4 encoding.
+			var offset uint8
+			if addrBits == 128 {
+				if len(b) < 1 {
+					return nil, fmt.Errorf("corebgp: truncated flowspec prefix component")
+				}
+				offset = b[0]
+				b = b[1:]
+			}
+			if int(offset) > prefixBits {
+				return nil, fmt.Errorf("corebgp: flowspec prefix offset %d exceeds length %d", offset, prefixBits)
+			}
+			byteLen := (prefixBits - int(offset) + 7) / 8
+			if len(b) < byteLen {
+				return nil, fmt.Errorf("corebgp: truncated flowspec prefix component")
+			}
+			prefix, err := decodeOffsetPrefixBits(b[:byteLen], int(offset), prefixBits, addrBits)
+			if err != nil {
+				return nil, err
+			}
+			components = append(components, FlowspecComponent{
+				Type: compType,
+				Prefix: &FlowspecPrefixComponent{
+					Type:   compType,
+					Prefix: prefix,
+					Offset: offset,
+				},
+			})
+			b = b[byteLen:]
+		default:
+			values, n, err := decodeFlowspecNumericValues(b)
+			if err != nil {
+				return nil, err
+			}
+			components = append(components, FlowspecComponent{Type: compType, Numeric: values})
+			b = b[n:]
+		}
+	}
+	return components, nil
+}
+
+// decodeOffsetPrefixBits decodes a prefixBits-long prefix whose pattern
+// octets encode only the bits from offset to prefixBits -- the RFC 8956
+// IPv6 flowspec prefix encoding -- placing them at the corresponding bit
+// position of an addrBits-wide address. offset is always 0 for the RFC 8955
+// IPv4 encoding, which carries the full prefix from bit 0.
+func decodeOffsetPrefixBits(pattern []byte, offset, prefixBits, addrBits int) (netip.Prefix, error) {
+	var ab [16]byte
+	for i := 0; i < prefixBits-offset; i++ {
+		if pattern[i/8]&(0x80>>uint(i%8)) != 0 {
+			pos := offset + i
+			ab[pos/8] |= 0x80 >> uint(pos%8)
+		}
+	}
+	switch addrBits {
+	case 32:
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(ab[:4])), prefixBits), nil
+	case 128:
+		return netip.PrefixFrom(netip.AddrFrom16(ab), prefixBits), nil
+	default:
+		return netip.Prefix{}, fmt.Errorf("corebgp: unsupported address width %d", addrBits)
+	}
+}
+
+func decodeFlowspecNumericValues(b []byte) ([]FlowspecNumericValue, int, error) {
+	var (
+		values []FlowspecNumericValue
+		n      int
+	)
+	for {
+		if len(b) < n+1 {
+			return nil, 0, fmt.Errorf("corebgp: truncated flowspec numeric component")
+		}
+		op := b[n]
+		valueLen := 1 << ((op >> flowspecNumericOpLenShift) & 0x3)
+		if len(b) < n+1+valueLen {
+			return nil, 0, fmt.Errorf("corebgp: truncated flowspec numeric component")
+		}
+		var value uint64
+		for i := 0; i < valueLen; i++ {
+			value = value<<8 | uint64(b[n+1+i])
+		}
+		values = append(values, FlowspecNumericValue{Op: op, Value: value})
+		n += 1 + valueLen
+		if op&FLOWSPEC_NUMERIC_OP_END != 0 {
+			return values, n, nil
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Capability codes for the Graceful Restart capability (RFC 4724) and its
+// Long-Lived Graceful Restart extension (RFC 9494).
+const (
+	CAP_CODE_GRACEFUL_RESTART = 64
+	CAP_CODE_LLGR             = 71
+)
+
+// graceful restart Restart Flags bits (RFC 4724 section 3), the top bits
+// of the first octet of the capability value.
+const (
+	GR_RESTART_FLAG_RESTARTED = 1 << 7
+)
+
+// graceful restart per-AFI/SAFI Flags bits (RFC 4724 section 3), the high
+// bit of the flags octet following each AFI/SAFI.
+const (
+	GR_FORWARDING_STATE_PRESERVED = 1 << 7
+)
+
+// GRAFISAFIEntry is a single AFI/SAFI entry of a GracefulRestartCapability,
+// indicating whether forwarding state was preserved across the restart for
+// that AFI/SAFI.
+type GRAFISAFIEntry struct {
+	AFI                      uint16
+	SAFI                     uint8
+	ForwardingStatePreserved bool
+}
+
+// GracefulRestartCapability is the Graceful Restart capability (RFC 4724).
+type GracefulRestartCapability struct {
+	// Restarted is the R bit: set by a speaker re-establishing a session
+	// after a restart to indicate that it has restarted. Whether forwarding
+	// state was preserved is reported per AFI/SAFI in AFISAFIEntries.
+	Restarted bool
+	// RestartTimeSeconds is the 12-bit advertised restart time, the
+	// maximum time the sender takes to reestablish the session after a
+	// failure.
+	RestartTimeSeconds uint16
+	AFISAFIEntries     []GRAFISAFIEntry
+}
+
+// DecodeGracefulRestartCapability decodes b, the value portion of a
+// CAP_CODE_GRACEFUL_RESTART capability.
+func DecodeGracefulRestartCapability(b []byte) (GracefulRestartCapability, error) {
+	if len(b) < 2 || (len(b)-2)%4 != 0 {
+		return GracefulRestartCapability{}, fmt.Errorf("corebgp: invalid graceful restart capability length %d", len(b))
+	}
+	var c GracefulRestartCapability
+	c.Restarted = b[0]&GR_RESTART_FLAG_RESTARTED != 0
+	c.RestartTimeSeconds = binary.BigEndian.Uint16(b[0:2]) &^ (0xf << 12)
+	for i := 2; i < len(b); i += 4 {
+		c.AFISAFIEntries = append(c.AFISAFIEntries, GRAFISAFIEntry{
+			AFI:                      binary.BigEndian.Uint16(b[i : i+2]),
+			SAFI:                     b[i+2],
+			ForwardingStatePreserved: b[i+3]&GR_FORWARDING_STATE_PRESERVED != 0,
+		})
+	}
+	return c, nil
+}
+
+// Encode returns the wire encoding of c as a capability, including the
+// capability code and length header.
+func (c GracefulRestartCapability) Encode() []byte {
+	value := make([]byte, 2, 2+4*len(c.AFISAFIEntries))
+	flagsAndTime := c.RestartTimeSeconds & 0x0fff
+	if c.Restarted {
+		flagsAndTime |= GR_RESTART_FLAG_RESTARTED << 8
+	}
+	binary.BigEndian.PutUint16(value[0:2], flagsAndTime)
+	for _, e := range c.AFISAFIEntries {
+		var eb [4]byte
+		binary.BigEndian.PutUint16(eb[0:2], e.AFI)
+		eb[2] = e.SAFI
+		if e.ForwardingStatePreserved {
+			eb[3] = GR_FORWARDING_STATE_PRESERVED
+		}
+		value = append(value, eb[:]...)
+	}
+	return append([]byte{CAP_CODE_GRACEFUL_RESTART, byte(len(value))}, value...)
+}
+
+// LLGRAFISAFIEntry is a single AFI/SAFI entry of an LLGRCapability.
+type LLGRAFISAFIEntry struct {
+	AFI                      uint16
+	SAFI                     uint8
+	ForwardingStatePreserved bool
+	// StaleTimeSeconds is the 24-bit advertised time the speaker retains
+	// stale routes for this AFI/SAFI beyond the ordinary Graceful Restart
+	// restart time.
+	StaleTimeSeconds uint32
+}
+
+// LLGRCapability is the Long-Lived Graceful Restart capability (RFC 9494).
+type LLGRCapability struct {
+	AFISAFIEntries []LLGRAFISAFIEntry
+}
+
+// DecodeLLGRCapability decodes b, the value portion of a CAP_CODE_LLGR
+// capability.
+func DecodeLLGRCapability(b []byte) (LLGRCapability, error) {
+	if len(b)%7 != 0 {
+		return LLGRCapability{}, fmt.Errorf("corebgp: invalid LLGR capability length %d", len(b))
+	}
+	var c LLGRCapability
+	for i := 0; i < len(b); i += 7 {
+		flags := b[i+3]
+		staleTime := uint32(b[i+4])<<16 | uint32(b[i+5])<<8 | uint32(b[i+6])
+		c.AFISAFIEntries = append(c.AFISAFIEntries, LLGRAFISAFIEntry{
+			AFI:                      binary.BigEndian.Uint16(b[i : i+2]),
+			SAFI:                     b[i+2],
+			ForwardingStatePreserved: flags&GR_FORWARDING_STATE_PRESERVED != 0,
+			StaleTimeSeconds:         staleTime,
+		})
+	}
+	return c, nil
+}
+
+// Encode returns the wire encoding of c as a capability, including the
+// capability code and length header.
+func (c LLGRCapability) Encode() []byte {
+	value := make([]byte, 0, 7*len(c.AFISAFIEntries))
+	for _, e := range c.AFISAFIEntries {
+		var eb [7]byte
+		binary.BigEndian.PutUint16(eb[0:2], e.AFI)
+		eb[2] = e.SAFI
+		if e.ForwardingStatePreserved {
+			eb[3] = GR_FORWARDING_STATE_PRESERVED
+		}
+		eb[4] = byte(e.StaleTimeSeconds >> 16)
+		eb[5] = byte(e.StaleTimeSeconds >> 8)
+		eb[6] = byte(e.StaleTimeSeconds)
+		value = append(value, eb[:]...)
+	}
+	return append([]byte{CAP_CODE_LLGR, byte(len(value))}, value...)
+}
+
+// NewEndOfRIBFn returns a MP_UNREACH_NLRI decode function, for use in place
+// of unreachFn when constructing a NewMPUnreachNLRIDecodeFn, that
+// recognizes an empty MP_UNREACH_NLRI -- an AFI/SAFI carrying no withdrawn
+// routes -- as an End-of-RIB marker (RFC 4724 section 2) and invokes fn(m,
+// afi, safi) instead of unreachFn. Any MP_UNREACH_NLRI with withdrawn
+// routes is forwarded to unreachFn unchanged.
+func NewEndOfRIBFn[M any](fn func(m M, afi uint16, safi uint8) error, unreachFn func(m M, afi uint16, safi uint8, withdrawn []byte) error) func(m M, afi uint16, safi uint8, withdrawn []byte) error {
+	return func(m M, afi uint16, safi uint8, withdrawn []byte) error {
+		if len(withdrawn) == 0 {
+			return fn(m, afi, safi)
+		}
+		return unreachFn(m, afi, safi, withdrawn)
+	}
+}
+
+// IsIPv4EndOfRIB reports whether b, the body of an UPDATE message, is an
+// AFI_IPV4/SAFI_UNICAST End-of-RIB marker (RFC 4724 section 2): zero
+// withdrawn routes, zero path attributes, and zero NLRI.
+func IsIPv4EndOfRIB(b []byte) bool {
+	return len(b) == 4 && b[0] == 0 && b[1] == 0 && b[2] == 0 && b[3] == 0
+}
+
+// GRState tracks, per session, the set of route keys that were present
+// before a peer with a negotiated Graceful Restart or Long-Lived Graceful
+// Restart capability went down. The session layer calls MarkStale with the
+// peer's Adj-RIB-In keys when the peer goes down, Refresh as routes are
+// readvertised once the peer reestablishes the session, and Sweep to
+// schedule removal of whatever remains stale once the negotiated
+// restart/stale time elapses.
+type GRState[K comparable] struct {
+	mu    sync.Mutex
+	stale map[K]struct{}
+	timer *time.Timer
+}
+
+// NewGRState returns an empty GRState.
+func NewGRState[K comparable]() *GRState[K] {
+	return &GRState[K]{stale: make(map[K]struct{})}
+}
+
+// MarkStale adds keys to the stale set.
+func (g *GRState[K]) MarkStale(keys ...K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, k := range keys {
+		g.stale[k] = struct{}{}
+	}
+}
+
+// Refresh removes key from the stale set, e.g. because the peer
+// reestablished the session and readvertised the route.
+func (g *GRState[K]) Refresh(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.stale, key)
+}
+
+// IsStale reports whether key is currently marked stale.
+func (g *GRState[K]) IsStale(key K) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.stale[key]
+	return ok
+}
+
+// Sweep schedules sweepFn to be called after d -- the peer's negotiated
+// restart time, or for LLGR the per-AFI/SAFI stale time -- with the keys
+// still marked stale at that point, after which the stale set is cleared.
+// Calling Sweep again before d elapses cancels the pending sweep and
+// replaces it.
+func (g *GRState[K]) Sweep(d time.Duration, sweepFn func(stale []K)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	g.timer = time.AfterFunc(d, func() {
+		g.mu.Lock()
+		stale := make([]K, 0, len(g.stale))
+		for k := range g.stale {
+			stale = append(stale, k)
+		}
+		g.stale = make(map[K]struct{})
+		g.mu.Unlock()
+		sweepFn(stale)
+	})
+}
+
+// Stop cancels any pending Sweep without invoking its callback.
+func (g *GRState[K]) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+}
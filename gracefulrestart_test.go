@@ -0,0 +1,102 @@
+package corebgp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGracefulRestartCapability_EncodeDecode(t *testing.T) {
+	c := GracefulRestartCapability{
+		Restarted:          true,
+		RestartTimeSeconds: 120,
+		AFISAFIEntries: []GRAFISAFIEntry{
+			{AFI: AFI_IPV6, SAFI: SAFI_UNICAST, ForwardingStatePreserved: true},
+		},
+	}
+	encoded := c.Encode()
+	if encoded[0] != CAP_CODE_GRACEFUL_RESTART {
+		t.Fatalf("want cap code %d, got %d", CAP_CODE_GRACEFUL_RESTART, encoded[0])
+	}
+	got, err := DecodeGracefulRestartCapability(encoded[2:])
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if !reflect.DeepEqual(c, got) {
+		t.Fatalf("want: %+v != got: %+v", c, got)
+	}
+}
+
+func TestLLGRCapability_EncodeDecode(t *testing.T) {
+	c := LLGRCapability{
+		AFISAFIEntries: []LLGRAFISAFIEntry{
+			{AFI: AFI_IPV6, SAFI: SAFI_UNICAST, ForwardingStatePreserved: true, StaleTimeSeconds: 3600},
+		},
+	}
+	encoded := c.Encode()
+	got, err := DecodeLLGRCapability(encoded[2:])
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if !reflect.DeepEqual(c, got) {
+		t.Fatalf("want: %+v != got: %+v", c, got)
+	}
+}
+
+func TestIsIPv4EndOfRIB(t *testing.T) {
+	if !IsIPv4EndOfRIB([]byte{0x00, 0x00, 0x00, 0x00}) {
+		t.Fatal("want true for empty UPDATE body")
+	}
+	if IsIPv4EndOfRIB([]byte{0x00, 0x01, 0x00, 0x00}) {
+		t.Fatal("want false for non-empty UPDATE body")
+	}
+}
+
+func TestNewEndOfRIBFn(t *testing.T) {
+	var gotEoR, gotUnreach bool
+	fn := NewEndOfRIBFn(
+		func(m *int, afi uint16, safi uint8) error {
+			gotEoR = true
+			return nil
+		},
+		func(m *int, afi uint16, safi uint8, withdrawn []byte) error {
+			gotUnreach = true
+			return nil
+		},
+	)
+	var m int
+	if err := fn(&m, AFI_IPV6, SAFI_UNICAST, nil); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if !gotEoR || gotUnreach {
+		t.Fatalf("want EoR callback only, got gotEoR=%v gotUnreach=%v", gotEoR, gotUnreach)
+	}
+
+	gotEoR, gotUnreach = false, false
+	if err := fn(&m, AFI_IPV6, SAFI_UNICAST, []byte{0x07, 0xfc}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if gotEoR || !gotUnreach {
+		t.Fatalf("want unreach callback only, got gotEoR=%v gotUnreach=%v", gotEoR, gotUnreach)
+	}
+}
+
+func TestGRState_Sweep(t *testing.T) {
+	g := NewGRState[string]()
+	g.MarkStale("10.0.0.0/8", "10.1.0.0/16")
+	g.Refresh("10.0.0.0/8")
+
+	done := make(chan []string, 1)
+	g.Sweep(10*time.Millisecond, func(stale []string) {
+		done <- stale
+	})
+
+	select {
+	case stale := <-done:
+		if !reflect.DeepEqual([]string{"10.1.0.0/16"}, stale) {
+			t.Fatalf("want [10.1.0.0/16], got %v", stale)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sweep callback was not invoked")
+	}
+}
@@ -0,0 +1,330 @@
+package corebgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// Additional AFI/SAFI values beyond AFI_IPV6/SAFI_UNICAST, covering the
+// families decoded in this file.
+const (
+	AFI_L2VPN = 25
+
+	SAFI_LABELED_UNICAST = 4
+	SAFI_MPLS_VPN        = 128
+	SAFI_EVPN            = 70
+	SAFI_FLOWSPEC        = 133
+)
+
+// mplsLabelBottomOfStack is the bottom-of-stack bit (RFC 3032) within the
+// third octet of a 3-octet MPLS label.
+const mplsLabelBottomOfStack = 0x01
+
+// decodeMPLSLabelStack decodes a stack of 3-octet MPLS labels from the head
+// of b, stopping after the label with the bottom-of-stack bit set. It
+// returns the decoded labels and the number of bytes consumed.
+func decodeMPLSLabelStack(b []byte) ([]uint32, int, error) {
+	var labels []uint32
+	var n int
+	for {
+		if len(b) < n+3 {
+			return nil, 0, fmt.Errorf("corebgp: truncated MPLS label")
+		}
+		label := uint32(b[n])<<12 | uint32(b[n+1])<<4 | uint32(b[n+2])>>4
+		bottomOfStack := b[n+2]&mplsLabelBottomOfStack != 0
+		labels = append(labels, label)
+		n += 3
+		if bottomOfStack {
+			return labels, n, nil
+		}
+		// a withdraw (RFC 8277 section 3) may signal "no label" with the
+		// reserved compatibility value 0x800000, which has no bottom-of-stack
+		// bit; treat it as a one-label stack rather than reading on into
+		// the prefix.
+		if b[n-3] == 0x80 && b[n-2] == 0x00 && b[n-1] == 0x00 {
+			return labels, n, nil
+		}
+	}
+}
+
+// encodeMPLSLabelStack encodes labels as a stack of 3-octet MPLS labels,
+// setting the bottom-of-stack bit on the final label.
+func encodeMPLSLabelStack(labels []uint32) []byte {
+	b := make([]byte, 0, 3*len(labels))
+	for i, label := range labels {
+		var lb [3]byte
+		lb[0] = byte(label >> 12)
+		lb[1] = byte(label >> 4)
+		lb[2] = byte(label<<4) & 0xf0
+		if i == len(labels)-1 {
+			lb[2] |= mplsLabelBottomOfStack
+		}
+		b = append(b, lb[:]...)
+	}
+	return b
+}
+
+// LabeledPrefix is a single RFC 8277 Labeled Unicast NLRI entry: one or
+// more MPLS labels followed by a prefix.
+type LabeledPrefix struct {
+	Labels []uint32
+	Prefix netip.Prefix
+}
+
+// decodeLabeledPrefix decodes a single labels+prefix entry from the head of
+// b for the given address family bit length (32 for IPv4, 128 for IPv6),
+// returning the entry and the number of bytes consumed.
+func decodeLabeledPrefix(b []byte, addrBits int) (LabeledPrefix, int, error) {
+	if len(b) < 1 {
+		return LabeledPrefix{}, 0, fmt.Errorf("corebgp: truncated labeled prefix")
+	}
+	totalBits := int(b[0])
+	labels, labelLen, err := decodeMPLSLabelStack(b[1:])
+	if err != nil {
+		return LabeledPrefix{}, 0, err
+	}
+	prefixBits := totalBits - 8*labelLen
+	if prefixBits < 0 || prefixBits > addrBits {
+		return LabeledPrefix{}, 0, fmt.Errorf("corebgp: invalid labeled prefix length %d", totalBits)
+	}
+	byteLen := (prefixBits + 7) / 8
+	start := 1 + labelLen
+	if len(b) < start+byteLen {
+		return LabeledPrefix{}, 0, fmt.Errorf("corebgp: truncated labeled prefix")
+	}
+	prefix, err := decodePrefixBits(b[start:start+byteLen], prefixBits, addrBits)
+	if err != nil {
+		return LabeledPrefix{}, 0, err
+	}
+	return LabeledPrefix{Labels: labels, Prefix: prefix}, start + byteLen, nil
+}
+
+// decodePrefixBits decodes a prefixBits-long prefix out of its minimal byte
+// encoding addrBytes, for an address family addrBits wide (32 or 128).
+func decodePrefixBits(addrBytes []byte, prefixBits, addrBits int) (netip.Prefix, error) {
+	switch addrBits {
+	case 32:
+		var ab [4]byte
+		copy(ab[:], addrBytes)
+		return netip.PrefixFrom(netip.AddrFrom4(ab), prefixBits), nil
+	case 128:
+		var ab [16]byte
+		copy(ab[:], addrBytes)
+		return netip.PrefixFrom(netip.AddrFrom16(ab), prefixBits), nil
+	default:
+		return netip.Prefix{}, fmt.Errorf("corebgp: unsupported address width %d", addrBits)
+	}
+}
+
+// DecodeMPIPv4LabeledUnicastNLRI decodes b as a series of RFC 8277 Labeled
+// Unicast NLRI entries for AFI_IPV4/SAFI_LABELED_UNICAST.
+func DecodeMPIPv4LabeledUnicastNLRI(b []byte) ([]LabeledPrefix, error) {
+	return decodeLabeledPrefixes(b, 32)
+}
+
+// DecodeMPIPv6LabeledUnicastNLRI decodes b as a series of RFC 8277 Labeled
+// Unicast NLRI entries for AFI_IPV6/SAFI_LABELED_UNICAST.
+func DecodeMPIPv6LabeledUnicastNLRI(b []byte) ([]LabeledPrefix, error) {
+	return decodeLabeledPrefixes(b, 128)
+}
+
+func decodeLabeledPrefixes(b []byte, addrBits int) ([]LabeledPrefix, error) {
+	var prefixes []LabeledPrefix
+	for len(b) > 0 {
+		lp, n, err := decodeLabeledPrefix(b, addrBits)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, lp)
+		b = b[n:]
+	}
+	return prefixes, nil
+}
+
+// EncodeMPLabeledPrefixes encodes prefixes as RFC 8277 Labeled Unicast
+// NLRI, suitable for use with EncodeMPReachNLRI/EncodeMPUnreachNLRI.
+func EncodeMPLabeledPrefixes(prefixes []LabeledPrefix) []byte {
+	var b []byte
+	for _, lp := range prefixes {
+		labelBytes := encodeMPLSLabelStack(lp.Labels)
+		prefixBits := lp.Prefix.Bits()
+		byteLen := (prefixBits + 7) / 8
+		b = append(b, uint8(8*len(labelBytes)+prefixBits))
+		b = append(b, labelBytes...)
+		if lp.Prefix.Addr().Is4() {
+			ab := lp.Prefix.Addr().As4()
+			b = append(b, ab[:byteLen]...)
+		} else {
+			ab := lp.Prefix.Addr().As16()
+			b = append(b, ab[:byteLen]...)
+		}
+	}
+	return b
+}
+
+// RouteDistinguisher is an 8-octet RFC 4364 Route Distinguisher, used to
+// disambiguate otherwise-overlapping prefixes across VPNs. Type 0 carries a
+// 2-octet ASN and 4-octet assigned number, type 1 a 4-octet IPv4 address
+// and 2-octet assigned number, and type 2 a 4-octet ASN and 2-octet
+// assigned number.
+type RouteDistinguisher struct {
+	Type  uint16
+	Value [6]byte
+}
+
+// String returns the conventional ASN:number or IP:number rendering of rd,
+// per its Type.
+func (rd RouteDistinguisher) String() string {
+	switch rd.Type {
+	case 0:
+		return fmt.Sprintf("%d:%d", binary.BigEndian.Uint16(rd.Value[0:2]), binary.BigEndian.Uint32(rd.Value[2:6]))
+	case 1:
+		ip := netip.AddrFrom4([4]byte(rd.Value[0:4]))
+		return fmt.Sprintf("%s:%d", ip, binary.BigEndian.Uint16(rd.Value[4:6]))
+	case 2:
+		return fmt.Sprintf("%d:%d", binary.BigEndian.Uint32(rd.Value[0:4]), binary.BigEndian.Uint16(rd.Value[4:6]))
+	default:
+		return fmt.Sprintf("unknown(%d):%x", rd.Type, rd.Value)
+	}
+}
+
+// decodeRouteDistinguisher decodes the 8-octet Route Distinguisher at the
+// head of b.
+func decodeRouteDistinguisher(b []byte) (RouteDistinguisher, error) {
+	if len(b) < 8 {
+		return RouteDistinguisher{}, fmt.Errorf("corebgp: truncated route distinguisher")
+	}
+	var rd RouteDistinguisher
+	rd.Type = binary.BigEndian.Uint16(b[0:2])
+	copy(rd.Value[:], b[2:8])
+	return rd, nil
+}
+
+// Encode returns the 8-octet wire encoding of rd.
+func (rd RouteDistinguisher) Encode() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b[0:2], rd.Type)
+	copy(b[2:8], rd.Value[:])
+	return b
+}
+
+// VPNPrefix is a single RFC 4364 MPLS/BGP VPN NLRI entry: one or more MPLS
+// labels, a Route Distinguisher, and a prefix.
+type VPNPrefix struct {
+	Labels []uint32
+	RD     RouteDistinguisher
+	Prefix netip.Prefix
+}
+
+func decodeVPNPrefixes(b []byte, addrBits int) ([]VPNPrefix, error) {
+	var prefixes []VPNPrefix
+	for len(b) > 0 {
+		if len(b) < 1 {
+			return nil, fmt.Errorf("corebgp: truncated VPN prefix")
+		}
+		totalBits := int(b[0])
+		labels, labelLen, err := decodeMPLSLabelStack(b[1:])
+		if err != nil {
+			return nil, err
+		}
+		rd, err := decodeRouteDistinguisher(b[1+labelLen:])
+		if err != nil {
+			return nil, err
+		}
+		prefixBits := totalBits - 8*labelLen - 8*8
+		if prefixBits < 0 || prefixBits > addrBits {
+			return nil, fmt.Errorf("corebgp: invalid VPN prefix length %d", totalBits)
+		}
+		byteLen := (prefixBits + 7) / 8
+		start := 1 + labelLen + 8
+		if len(b) < start+byteLen {
+			return nil, fmt.Errorf("corebgp: truncated VPN prefix")
+		}
+		prefix, err := decodePrefixBits(b[start:start+byteLen], prefixBits, addrBits)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, VPNPrefix{Labels: labels, RD: rd, Prefix: prefix})
+		b = b[start+byteLen:]
+	}
+	return prefixes, nil
+}
+
+// DecodeMPVPNIPv4NLRI decodes b as a series of RFC 4364 VPN-IPv4 NLRI
+// entries for AFI_IPV4/SAFI_MPLS_VPN.
+func DecodeMPVPNIPv4NLRI(b []byte) ([]VPNPrefix, error) {
+	return decodeVPNPrefixes(b, 32)
+}
+
+// DecodeMPVPNIPv6NLRI decodes b as a series of RFC 4659 VPN-IPv6 NLRI
+// entries for AFI_IPV6/SAFI_MPLS_VPN.
+func DecodeMPVPNIPv6NLRI(b []byte) ([]VPNPrefix, error) {
+	return decodeVPNPrefixes(b, 128)
+}
+
+// EncodeMPVPNPrefixes encodes prefixes as RFC 4364/RFC 4659 VPN NLRI,
+// suitable for use with EncodeMPReachNLRI/EncodeMPUnreachNLRI.
+func EncodeMPVPNPrefixes(prefixes []VPNPrefix) []byte {
+	var b []byte
+	for _, vp := range prefixes {
+		labelBytes := encodeMPLSLabelStack(vp.Labels)
+		rdBytes := vp.RD.Encode()
+		prefixBits := vp.Prefix.Bits()
+		byteLen := (prefixBits + 7) / 8
+		b = append(b, uint8(8*len(labelBytes)+8*len(rdBytes)+prefixBits))
+		b = append(b, labelBytes...)
+		b = append(b, rdBytes...)
+		if vp.Prefix.Addr().Is4() {
+			ab := vp.Prefix.Addr().As4()
+			b = append(b, ab[:byteLen]...)
+		} else {
+			ab := vp.Prefix.Addr().As16()
+			b = append(b, ab[:byteLen]...)
+		}
+	}
+	return b
+}
+
+// EncodeMPVPNIPv4NextHop encodes an RFC 4364 VPN-IPv4 next hop: an 8-octet
+// zero Route Distinguisher followed by the IPv4 next hop address, preceded
+// by its length octet.
+func EncodeMPVPNIPv4NextHop(nh netip.Addr) ([]byte, error) {
+	if !nh.Is4() {
+		return nil, fmt.Errorf("corebgp: EncodeMPVPNIPv4NextHop: %s is not an IPv4 address", nh)
+	}
+	ab := nh.As4()
+	b := make([]byte, 0, 12)
+	b = append(b, make([]byte, 8)...)
+	b = append(b, ab[:]...)
+	return append([]byte{uint8(len(b))}, b...), nil
+}
+
+// EncodeMPVPNIPv6NextHop encodes an RFC 4659 VPN-IPv6 next hop: an 8-octet
+// zero Route Distinguisher followed by the IPv6 next hop address, preceded
+// by its length octet.
+func EncodeMPVPNIPv6NextHop(nh netip.Addr) ([]byte, error) {
+	if !nh.Is6() {
+		return nil, fmt.Errorf("corebgp: EncodeMPVPNIPv6NextHop: %s is not an IPv6 address", nh)
+	}
+	ab := nh.As16()
+	b := make([]byte, 0, 24)
+	b = append(b, make([]byte, 8)...)
+	b = append(b, ab[:]...)
+	return append([]byte{uint8(len(b))}, b...), nil
+}
+
+// DecodeMPVPNNextHop decodes b, the next hop portion of a MP_REACH_NLRI
+// path attribute for SAFI_MPLS_VPN, discarding the leading Route
+// Distinguisher (which RFC 4364/RFC 4659 require to be zero) and returning
+// the IPv4 or IPv6 next hop address.
+func DecodeMPVPNNextHop(b []byte) (netip.Addr, error) {
+	if len(b) != 12 && len(b) != 24 {
+		return netip.Addr{}, fmt.Errorf("corebgp: VPN next hop length %d is neither 12 nor 24", len(b))
+	}
+	addr, ok := netip.AddrFromSlice(b[8:])
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("corebgp: invalid VPN next hop address")
+	}
+	return addr, nil
+}
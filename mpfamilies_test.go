@@ -0,0 +1,176 @@
+package corebgp
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeMPIPv4LabeledUnicastNLRI(t *testing.T) {
+	// label 100, bottom-of-stack set, prefix 10.0.0.0/8
+	label := []byte{0x00, 0x06, 0x41} // (100<<4)|1 = 0x641
+	nlri := append([]byte{uint8(8*3 + 8)}, label...)
+	nlri = append(nlri, 0x0a)
+	got, err := DecodeMPIPv4LabeledUnicastNLRI(nlri)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	want := []LabeledPrefix{
+		{Labels: []uint32{100}, Prefix: netip.MustParsePrefix("10.0.0.0/8")},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %+v != got: %+v", want, got)
+	}
+}
+
+func TestRouteDistinguisher_String(t *testing.T) {
+	rd := RouteDistinguisher{Type: 0, Value: [6]byte{0xfd, 0xea, 0x00, 0x00, 0x00, 0x01}}
+	if got, want := rd.String(), "65002:1"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestDecodeMPVPNIPv4NLRI(t *testing.T) {
+	label := []byte{0x00, 0x06, 0x41} // label 100, bottom-of-stack
+	rd := RouteDistinguisher{Type: 0, Value: [6]byte{0xfd, 0xea, 0x00, 0x00, 0x00, 0x01}}
+	entry := append([]byte{uint8(8*len(label) + 8*8 + 8)}, label...)
+	entry = append(entry, rd.Encode()...)
+	entry = append(entry, 0x0a)
+	got, err := DecodeMPVPNIPv4NLRI(entry)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	want := []VPNPrefix{
+		{Labels: []uint32{100}, RD: rd, Prefix: netip.MustParsePrefix("10.0.0.0/8")},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %+v != got: %+v", want, got)
+	}
+}
+
+func TestDecodeMPEVPNNLRI_MACIPAdvertisement(t *testing.T) {
+	value := make([]byte, 0, 33)
+	rd := RouteDistinguisher{Type: 0, Value: [6]byte{0xfd, 0xea, 0x00, 0x00, 0x00, 0x01}}
+	value = append(value, rd.Encode()...)
+	value = append(value, make([]byte, 10)...)    // ESI
+	value = append(value, 0x00, 0x00, 0x00, 0x00) // ethernet tag
+	value = append(value, 48)                     // MAC address length (bits)
+	value = append(value, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55)
+	value = append(value, 0)                // IP address length: none
+	value = append(value, 0x00, 0x06, 0x41) // single label 100
+
+	nlri := append([]byte{EVPN_ROUTE_TYPE_MAC_IP_ADVERTISEMENT, uint8(len(value))}, value...)
+	got, err := DecodeMPEVPNNLRI(nlri)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if len(got) != 1 || got[0].MACIPAdvertisement == nil {
+		t.Fatalf("got: %+v", got)
+	}
+	m := got[0].MACIPAdvertisement
+	if m.MAC != ([6]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}) {
+		t.Fatalf("unexpected MAC: %x", m.MAC)
+	}
+	if len(m.Labels) != 1 || m.Labels[0] != 100 {
+		t.Fatalf("unexpected labels: %+v", m.Labels)
+	}
+}
+
+func TestDecodeMPEVPNNLRI_IPPrefix(t *testing.T) {
+	value := make([]byte, 0, 34)
+	rd := RouteDistinguisher{Type: 0, Value: [6]byte{0xfd, 0xea, 0x00, 0x00, 0x00, 0x01}}
+	value = append(value, rd.Encode()...)
+	value = append(value, make([]byte, 10)...)    // ESI
+	value = append(value, 0x00, 0x00, 0x00, 0x00) // ethernet tag
+	value = append(value, 24)                     // IP prefix length (bits)
+	value = append(value, 0x0a, 0x00, 0x00, 0x00) // prefix 10.0.0.0/24
+	value = append(value, 0x00, 0x00, 0x00, 0x00) // gateway IP: none
+	value = append(value, 0x00, 0x06, 0x41)       // single label 100
+
+	nlri := append([]byte{EVPN_ROUTE_TYPE_IP_PREFIX, uint8(len(value))}, value...)
+	got, err := DecodeMPEVPNNLRI(nlri)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if len(got) != 1 || got[0].IPPrefix == nil {
+		t.Fatalf("got: %+v", got)
+	}
+	p := got[0].IPPrefix
+	if p.Prefix.String() != "10.0.0.0/24" {
+		t.Fatalf("unexpected prefix: %s", p.Prefix)
+	}
+	if p.Label != 100 {
+		t.Fatalf("unexpected label: %d", p.Label)
+	}
+}
+
+func TestDecodeMPFlowspecNLRI(t *testing.T) {
+	// destination prefix 10.0.0.0/8, then IP protocol = 6 (TCP)
+	value := []byte{
+		FLOWSPEC_COMPONENT_DST_PREFIX, 8, 0x0a,
+		FLOWSPEC_COMPONENT_IP_PROTOCOL, FLOWSPEC_NUMERIC_OP_END | FLOWSPEC_NUMERIC_OP_EQ, 6,
+	}
+	nlri := append([]byte{uint8(len(value))}, value...)
+	got, err := DecodeMPFlowspecNLRI(nlri, 32)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if len(got) != 1 || len(got[0].Components) != 2 {
+		t.Fatalf("got: %+v", got)
+	}
+	if got[0].Components[0].Prefix == nil || got[0].Components[0].Prefix.Prefix.String() != "10.0.0.0/8" {
+		t.Fatalf("unexpected dst prefix component: %+v", got[0].Components[0])
+	}
+	if len(got[0].Components[1].Numeric) != 1 || got[0].Components[1].Numeric[0].Value != 6 {
+		t.Fatalf("unexpected protocol component: %+v", got[0].Components[1])
+	}
+}
+
+func TestDecodeMPFlowspecNLRI_IPv6(t *testing.T) {
+	// destination prefix 2001:db8::/32 with offset 0
+	value := []byte{
+		FLOWSPEC_COMPONENT_DST_PREFIX, 32, 0,
+		0x20, 0x01, 0x0d, 0xb8,
+	}
+	nlri := append([]byte{uint8(len(value))}, value...)
+	got, err := DecodeMPFlowspecNLRI(nlri, 128)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if len(got) != 1 || len(got[0].Components) != 1 {
+		t.Fatalf("got: %+v", got)
+	}
+	c := got[0].Components[0].Prefix
+	if c == nil || c.Prefix.String() != "2001:db8::/32" {
+		t.Fatalf("unexpected dst prefix component: %+v", c)
+	}
+	if c.Offset != 0 {
+		t.Fatalf("unexpected offset: %d", c.Offset)
+	}
+}
+
+func TestDecodeMPFlowspecNLRI_IPv6Offset(t *testing.T) {
+	// a /48 destination prefix with offset 32: the pattern carries only
+	// bits 32 through 47, i.e. the octets 0x12, 0x34, placed starting at
+	// bit 32 of the reconstructed address; bits 0 through 31 are not
+	// carried and decode as zero.
+	value := []byte{
+		FLOWSPEC_COMPONENT_DST_PREFIX, 48, 32,
+		0x12, 0x34,
+	}
+	nlri := append([]byte{uint8(len(value))}, value...)
+	got, err := DecodeMPFlowspecNLRI(nlri, 128)
+	if err != nil {
+		t.Fatalf("decode err = %v", err)
+	}
+	if len(got) != 1 || len(got[0].Components) != 1 {
+		t.Fatalf("got: %+v", got)
+	}
+	c := got[0].Components[0].Prefix
+	if c == nil || c.Prefix.String() != "0:0:1234::/48" {
+		t.Fatalf("unexpected dst prefix component: %+v", c)
+	}
+	if c.Offset != 32 {
+		t.Fatalf("unexpected offset: %d", c.Offset)
+	}
+}